@@ -0,0 +1,132 @@
+package lookup
+
+import (
+	"context"
+	"iter"
+	"sync"
+
+	"github.com/Station-Manager/types"
+)
+
+// DefaultBatchWorkers is the worker limit used when BatchOptions.Workers is unset.
+const DefaultBatchWorkers = 8
+
+// BatchOptions configures LookupMany and LookupManyStations.
+type BatchOptions struct {
+	// Workers caps the number of lookups in flight at once.
+	Workers int
+}
+
+// BatchResult is the outcome of one distinct callsign in a batch.
+type BatchResult[T any] struct {
+	// Callsign is the normalised callsign (see NormalizeCallsign).
+	Callsign string
+	// Indexes are the positions in the input at which the callsign appeared.
+	Indexes []int
+	Value   T
+	Err     error
+}
+
+// LookupMany resolves callsigns with p using up to opts.Workers concurrent lookups and
+// yields one result per distinct callsign as each completes. Repeated callsigns are
+// looked up once. Failures are reported per callsign and do not stop the batch; if ctx is
+// cancelled, the callsigns not yet looked up are yielded with the context's error.
+// Stopping the iteration early cancels the outstanding lookups.
+func LookupMany(ctx context.Context, p Provider, callsigns []string, opts BatchOptions) iter.Seq[BatchResult[types.Country]] {
+	return lookupMany(ctx, callsigns, opts, p.LookupWithContext)
+}
+
+// LookupManyStations is LookupMany for a StationProvider.
+func LookupManyStations(ctx context.Context, p StationProvider, callsigns []string, opts BatchOptions) iter.Seq[BatchResult[types.ContactedStation]] {
+	return lookupMany(ctx, callsigns, opts, p.LookupWithContext)
+}
+
+func lookupMany[T any](ctx context.Context, callsigns []string, opts BatchOptions, fetch func(context.Context, string) (T, error)) iter.Seq[BatchResult[T]] {
+	return func(yield func(BatchResult[T]) bool) {
+		parent := ctx
+		if parent == nil {
+			parent = context.Background()
+		}
+
+		jobs := dedupCallsigns[T](callsigns)
+		if len(jobs) == 0 {
+			return
+		}
+		workers := opts.Workers
+		if workers <= 0 {
+			workers = DefaultBatchWorkers
+		}
+		if workers > len(jobs) {
+			workers = len(jobs)
+		}
+
+		// stop is closed when the consumer stops iterating. It is kept apart from ctx so
+		// that a cancelled parent context still reports every remaining callsign.
+		stop := make(chan struct{})
+		lookupCtx, cancel := context.WithCancel(parent)
+		defer cancel()
+
+		queue := make(chan *BatchResult[T])
+		results := make(chan *BatchResult[T])
+
+		go func() {
+			defer close(queue)
+			for _, job := range jobs {
+				select {
+				case queue <- job:
+				case <-stop:
+					return
+				}
+			}
+		}()
+
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for job := range queue {
+					if err := lookupCtx.Err(); err != nil {
+						job.Err = err
+					} else {
+						job.Value, job.Err = fetch(lookupCtx, job.Callsign)
+					}
+					select {
+					case results <- job:
+					case <-stop:
+						return
+					}
+				}
+			}()
+		}
+		go func() {
+			wg.Wait()
+			close(results)
+		}()
+
+		for r := range results {
+			if !yield(*r) {
+				close(stop)
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// dedupCallsigns groups the input by normalised callsign, preserving first-seen order.
+func dedupCallsigns[T any](callsigns []string) []*BatchResult[T] {
+	byKey := make(map[string]*BatchResult[T], len(callsigns))
+	jobs := make([]*BatchResult[T], 0, len(callsigns))
+	for i, callsign := range callsigns {
+		key := cacheKey(callsign)
+		if job, ok := byKey[key]; ok {
+			job.Indexes = append(job.Indexes, i)
+			continue
+		}
+		job := &BatchResult[T]{Callsign: key, Indexes: []int{i}}
+		byKey[key] = job
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
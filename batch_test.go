@@ -0,0 +1,104 @@
+package lookup
+
+import (
+	"context"
+	stderrors "errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Station-Manager/types"
+)
+
+func TestLookupMany_DedupsAndReportsPerCallsign(t *testing.T) {
+	var calls atomic.Int32
+	p := funcProvider(func(_ context.Context, callsign string) (types.Country, error) {
+		calls.Add(1)
+		if callsign == "QQ1ABC" {
+			return types.Country{}, stderrors.New("boom")
+		}
+		return types.Country{Name: callsign}, nil
+	})
+
+	input := []string{"K1ABC", "k1abc", "QQ1ABC", "DL1XX", " K1ABC ", "DLØXX", "DL0XX"}
+	got := make(map[string]BatchResult[types.Country])
+	for r := range LookupMany(context.Background(), p, input, BatchOptions{Workers: 2}) {
+		got[r.Callsign] = r
+	}
+
+	if len(got) != 4 || calls.Load() != 4 {
+		t.Fatalf("expected 4 distinct lookups, got %d results and %d calls", len(got), calls.Load())
+	}
+	if r := got["K1ABC"]; r.Err != nil || r.Value.Name != "K1ABC" || len(r.Indexes) != 3 {
+		t.Fatalf("unexpected K1ABC result: %#v", r)
+	}
+	if r := got["QQ1ABC"]; r.Err == nil {
+		t.Fatalf("expected QQ1ABC to carry its error")
+	}
+	if r := got["DL1XX"]; r.Err != nil || r.Value.Name != "DL1XX" {
+		t.Fatalf("expected DL1XX to succeed despite another failure, got %#v", r)
+	}
+	if r := got["DL0XX"]; len(r.Indexes) != 2 {
+		t.Fatalf("expected DLØXX and DL0XX to share a lookup, got %#v", r)
+	}
+}
+
+func TestLookupMany_RespectsWorkerLimit(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	p := funcProvider(func(_ context.Context, callsign string) (types.Country, error) {
+		n := inFlight.Add(1)
+		for {
+			old := peak.Load()
+			if n <= old || peak.CompareAndSwap(old, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		inFlight.Add(-1)
+		return types.Country{Name: callsign}, nil
+	})
+
+	input := []string{"A1A", "B1B", "C1C", "D1D", "E1E", "F1F", "G1G", "H1H"}
+	n := 0
+	for range LookupMany(context.Background(), p, input, BatchOptions{Workers: 3}) {
+		n++
+	}
+	if n != len(input) {
+		t.Fatalf("expected %d results, got %d", len(input), n)
+	}
+	if got := peak.Load(); got > 3 {
+		t.Fatalf("expected at most 3 concurrent lookups, got %d", got)
+	}
+}
+
+func TestLookupMany_CancelledContextReportsRemaining(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var calls atomic.Int32
+	p := funcProvider(func(_ context.Context, callsign string) (types.Country, error) {
+		calls.Add(1)
+		return types.Country{Name: callsign}, nil
+	})
+
+	n := 0
+	for r := range LookupMany(ctx, p, []string{"A1A", "B1B", "C1C"}, BatchOptions{}) {
+		if !stderrors.Is(r.Err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", r.Err)
+		}
+		n++
+	}
+	if n != 3 || calls.Load() != 0 {
+		t.Fatalf("expected 3 cancelled results and no lookups, got %d results and %d calls", n, calls.Load())
+	}
+}
+
+func TestLookupMany_EarlyBreak(t *testing.T) {
+	p := funcProvider(func(ctx context.Context, callsign string) (types.Country, error) {
+		return types.Country{Name: callsign}, nil
+	})
+
+	for range LookupMany(context.Background(), p, []string{"A1A", "B1B", "C1C", "D1D"}, BatchOptions{Workers: 1}) {
+		break
+	}
+}
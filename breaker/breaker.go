@@ -0,0 +1,293 @@
+// Package breaker provides the circuit breaker the HTTP lookup providers put in front of
+// their upstream services, so that lookups fail fast while a service is down instead of
+// each waiting for the HTTP timeout.
+package breaker
+
+import (
+	"context"
+	stderr "errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Station-Manager/lookup/internal/upstream"
+)
+
+const (
+	// DefaultFailureThreshold is used when Config.FailureThreshold is zero.
+	DefaultFailureThreshold = 5
+	// DefaultOpenFor is used when Config.OpenFor is zero.
+	DefaultOpenFor = 30 * time.Second
+	// DefaultProbes is used when Config.Probes is zero.
+	DefaultProbes = 1
+)
+
+// State is the state of a breaker as shown to the user.
+type State int
+
+const (
+	// Closed: requests flow normally.
+	Closed State = iota
+	// HalfOpen: the open period has elapsed and probe requests are testing the upstream.
+	HalfOpen
+	// Open: requests are refused without contacting the upstream.
+	Open
+	// Disabled is reported by providers switched off through LookupConfig.Enabled. A
+	// Breaker itself never enters this state.
+	Disabled
+)
+
+// String returns a lower-case name suitable for display.
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case HalfOpen:
+		return "half-open"
+	case Open:
+		return "open"
+	case Disabled:
+		return "disabled"
+	default:
+		return "unknown"
+	}
+}
+
+// Config configures a Breaker. Zero fields select the package defaults.
+type Config struct {
+	// FailureThreshold is the number of consecutive failures that opens the breaker. A
+	// negative value disables the breaker.
+	FailureThreshold int `json:"failure_threshold"`
+	// OpenFor is how long the breaker stays open before letting a probe through.
+	OpenFor time.Duration `json:"open_for"`
+	// Probes is the number of consecutive successful probes that closes the breaker
+	// again. Probes are let through one at a time.
+	Probes int `json:"probes"`
+	// OnStateChange, if set, is called (without the breaker's lock held) after every
+	// state change, e.g. to update a status bar.
+	OnStateChange func(from, to State) `json:"-"`
+}
+
+// ErrOpen is matched (via errors.Is) by every OpenError.
+var ErrOpen = stderr.New("circuit breaker open")
+
+// OpenError is returned by Allow while the breaker refuses requests.
+type OpenError struct {
+	// RetryIn is how long until the breaker lets a probe through; zero while a probe is
+	// already in flight.
+	RetryIn time.Duration
+}
+
+// Error implements the error interface.
+func (e *OpenError) Error() string {
+	if e.RetryIn > 0 {
+		return "circuit breaker open, retry in " + e.RetryIn.Round(time.Millisecond).String()
+	}
+	return "circuit breaker open, probe in progress"
+}
+
+// Is reports whether target is ErrOpen. An open breaker also matches the shared
+// upstream-unavailable sentinel (lookup.ErrUnavailable).
+func (e *OpenError) Is(target error) bool {
+	return target == ErrOpen || target == upstream.ErrUnavailable
+}
+
+// Temporary always reports true: the breaker closes again once the upstream recovers.
+func (e *OpenError) Temporary() bool {
+	return true
+}
+
+// Breaker is a three-state circuit breaker. A nil *Breaker lets every request through.
+type Breaker struct {
+	cfg Config
+	now func() time.Time
+
+	mu       sync.Mutex
+	state    State
+	failures int       // consecutive failures while closed
+	openedAt time.Time // when the breaker last opened
+	probing  bool      // a half-open probe is in flight
+	probeOK  int       // consecutive successful probes while half-open
+}
+
+// New returns a closed breaker for cfg, or nil if cfg disables it.
+func New(cfg Config) *Breaker {
+	if cfg.FailureThreshold < 0 {
+		return nil
+	}
+	if cfg.FailureThreshold == 0 {
+		cfg.FailureThreshold = DefaultFailureThreshold
+	}
+	if cfg.OpenFor <= 0 {
+		cfg.OpenFor = DefaultOpenFor
+	}
+	if cfg.Probes <= 0 {
+		cfg.Probes = DefaultProbes
+	}
+	return &Breaker{cfg: cfg, now: time.Now}
+}
+
+// State returns the current state. An open breaker whose open period has elapsed reports
+// HalfOpen.
+func (b *Breaker) State() State {
+	if b == nil {
+		return Closed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == Open && b.now().Sub(b.openedAt) >= b.cfg.OpenFor {
+		return HalfOpen
+	}
+	return b.state
+}
+
+// Allow asks to make a request. If the breaker refuses, it returns an *OpenError.
+// Otherwise the caller must make the request and pass its outcome to done.
+func (b *Breaker) Allow() (done func(err error), err error) {
+	record, err := b.admit()
+	if err != nil {
+		return nil, err
+	}
+	return func(err error) { record(classify(err)) }, nil
+}
+
+// Do runs fn if the breaker allows it and records the outcome. ctx is the context fn
+// runs under: an error caused by it ending, because the caller gave up or ran out of
+// time, says nothing about the upstream and is not counted.
+func (b *Breaker) Do(ctx context.Context, fn func() error) error {
+	record, err := b.admit()
+	if err != nil {
+		return err
+	}
+	err = fn()
+	if err != nil && ctx != nil && ctx.Err() != nil && stderr.Is(err, ctx.Err()) {
+		record(ignored)
+	} else {
+		record(classify(err))
+	}
+	return err
+}
+
+// admit implements Allow, returning a function that records the request's outcome.
+func (b *Breaker) admit() (func(outcome), error) {
+	if b == nil {
+		return func(outcome) {}, nil
+	}
+
+	b.mu.Lock()
+	from := b.state
+	now := b.now()
+	switch b.state {
+	case Open:
+		if wait := b.cfg.OpenFor - now.Sub(b.openedAt); wait > 0 {
+			b.mu.Unlock()
+			return nil, &OpenError{RetryIn: wait}
+		}
+		b.state = HalfOpen
+		b.probeOK = 0
+		fallthrough
+	case HalfOpen:
+		if b.probing {
+			b.mu.Unlock()
+			return nil, &OpenError{}
+		}
+		b.probing = true
+	}
+	to := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+
+	probe := to == HalfOpen
+	var once sync.Once
+	return func(o outcome) { once.Do(func() { b.record(probe, o) }) }, nil
+}
+
+// Reset closes the breaker and clears its failure count.
+func (b *Breaker) Reset() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	from := b.state
+	b.state, b.failures, b.probing, b.probeOK = Closed, 0, false, 0
+	b.mu.Unlock()
+	b.notify(from, Closed)
+}
+
+// record applies the outcome of a request let through by Allow.
+func (b *Breaker) record(probe bool, outcome outcome) {
+	b.mu.Lock()
+	from := b.state
+	if probe {
+		b.probing = false
+	}
+	switch {
+	case b.state == HalfOpen && probe:
+		switch outcome {
+		case failure:
+			b.trip()
+		case success:
+			if b.probeOK++; b.probeOK >= b.cfg.Probes {
+				b.state, b.failures = Closed, 0
+			}
+		}
+	case b.state == Closed:
+		switch outcome {
+		case failure:
+			if b.failures++; b.failures >= b.cfg.FailureThreshold {
+				b.trip()
+			}
+		case success:
+			b.failures = 0
+		}
+	}
+	to := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+}
+
+// trip opens the breaker. b.mu must be held.
+func (b *Breaker) trip() {
+	b.state = Open
+	b.openedAt = b.now()
+	b.failures, b.probeOK = 0, 0
+}
+
+func (b *Breaker) notify(from, to State) {
+	if from != to && b.cfg.OnStateChange != nil {
+		b.cfg.OnStateChange(from, to)
+	}
+}
+
+type outcome int
+
+const (
+	success outcome = iota
+	failure
+	// ignored outcomes say nothing about the upstream's health: the caller gave up, or
+	// the request was held back before it was sent.
+	ignored
+)
+
+// classify decides what a request's error says about the upstream. Answers, including
+// not-found and client errors such as rejected credentials, show it is up. Connection
+// failures, timeouts and 5xx responses count against it.
+func classify(err error) outcome {
+	if err == nil {
+		return success
+	}
+	if stderr.Is(err, context.Canceled) || stderr.Is(err, upstream.ErrRateLimited) || stderr.Is(err, ErrOpen) {
+		return ignored
+	}
+	var se *upstream.StatusError
+	if stderr.As(err, &se) {
+		if se.StatusCode >= http.StatusInternalServerError {
+			return failure
+		}
+		return success
+	}
+	if upstream.IsConnectionFailure(err) {
+		return failure
+	}
+	return success
+}
@@ -0,0 +1,130 @@
+package breaker
+
+import (
+	"context"
+	stderrors "errors"
+	"net/url"
+	"testing"
+	"time"
+
+	smerrors "github.com/Station-Manager/errors"
+	"github.com/Station-Manager/lookup/internal/upstream"
+)
+
+var errBadGateway = &upstream.StatusError{StatusCode: 502}
+
+// newTestBreaker returns a breaker driven by a fake clock.
+func newTestBreaker(cfg Config) (*Breaker, *time.Time) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := New(cfg)
+	b.now = func() time.Time { return now }
+	return b, &now
+}
+
+func TestBreaker_OpensAfterConsecutiveFailures(t *testing.T) {
+	var changes []State
+	b, _ := newTestBreaker(Config{FailureThreshold: 3, OnStateChange: func(_, to State) { changes = append(changes, to) }})
+
+	_ = b.Do(context.Background(), func() error { return errBadGateway })
+	_ = b.Do(context.Background(), func() error { return nil }) // a success resets the count
+	for i := 0; i < 3; i++ {
+		_ = b.Do(context.Background(), func() error { return errBadGateway })
+	}
+	if b.State() != Open {
+		t.Fatalf("expected open, got %v", b.State())
+	}
+
+	called := false
+	err := b.Do(context.Background(), func() error { called = true; return nil })
+	var oe *OpenError
+	if called || !stderrors.As(err, &oe) || !stderrors.Is(err, ErrOpen) || oe.RetryIn != DefaultOpenFor {
+		t.Fatalf("expected a fast OpenError, got %v (called=%v)", err, called)
+	}
+	if len(changes) != 1 || changes[0] != Open {
+		t.Fatalf("unexpected state changes: %v", changes)
+	}
+}
+
+func TestBreaker_HalfOpenProbe(t *testing.T) {
+	b, now := newTestBreaker(Config{FailureThreshold: 1, OpenFor: time.Minute})
+	_ = b.Do(context.Background(), func() error { return errBadGateway })
+
+	*now = now.Add(time.Minute)
+	if b.State() != HalfOpen {
+		t.Fatalf("expected half-open, got %v", b.State())
+	}
+
+	// Only one probe at a time.
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatalf("expected the probe to be allowed, got %v", err)
+	}
+	if _, err = b.Allow(); !stderrors.Is(err, ErrOpen) {
+		t.Fatalf("expected a second request to be refused during the probe, got %v", err)
+	}
+
+	// A failed probe reopens the breaker.
+	done(errBadGateway)
+	if b.State() != Open {
+		t.Fatalf("expected open after a failed probe, got %v", b.State())
+	}
+
+	// A successful probe closes it.
+	*now = now.Add(time.Minute)
+	if err = b.Do(context.Background(), func() error { return nil }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.State() != Closed {
+		t.Fatalf("expected closed after a successful probe, got %v", b.State())
+	}
+}
+
+func TestBreaker_IgnoresAnswersAndCancellations(t *testing.T) {
+	b, _ := newTestBreaker(Config{FailureThreshold: 1})
+	for _, err := range []error{
+		smerrors.New("test").Err(smerrors.ErrNotFound).Msg("not found"),
+		&upstream.StatusError{StatusCode: 401},
+		&upstream.RateLimitError{StatusCode: 429},
+		context.Canceled,
+	} {
+		_ = b.Do(context.Background(), func() error { return err })
+	}
+	if b.State() != Closed {
+		t.Fatalf("expected closed, got %v", b.State())
+	}
+}
+
+func TestBreaker_NilAndDisabled(t *testing.T) {
+	if b := New(Config{FailureThreshold: -1}); b != nil {
+		t.Fatalf("expected a negative threshold to disable the breaker")
+	}
+	var b *Breaker
+	if err := b.Do(context.Background(), func() error { return errBadGateway }); err != errBadGateway || b.State() != Closed {
+		t.Fatalf("expected a nil breaker to pass everything through, got %v", err)
+	}
+}
+
+func TestBreaker_IgnoresCallerDeadline(t *testing.T) {
+	b, _ := newTestBreaker(Config{FailureThreshold: 1})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+
+	// The caller running out of time is not the upstream's fault.
+	err := b.Do(ctx, func() error {
+		return smerrors.New("test").Err(&url.Error{Op: "Get", URL: "http://upstream", Err: ctx.Err()}).Msg("request failed")
+	})
+	if !stderrors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline error to be returned, got %v", err)
+	}
+	if b.State() != Closed {
+		t.Fatalf("expected closed after a caller deadline, got %v", b.State())
+	}
+
+	// An upstream timeout under a live context still counts.
+	_ = b.Do(context.Background(), func() error { return context.DeadlineExceeded })
+	if b.State() != Open {
+		t.Fatalf("expected open after an upstream timeout, got %v", b.State())
+	}
+}
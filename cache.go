@@ -0,0 +1,433 @@
+package lookup
+
+import (
+	"container/list"
+	"context"
+	stderr "errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Station-Manager/errors"
+	"github.com/Station-Manager/lookup/internal/calls"
+	"github.com/Station-Manager/lookup/metrics"
+	"github.com/Station-Manager/types"
+	"github.com/goccy/go-json"
+)
+
+const (
+	// DefaultCacheMaxEntries is the LRU capacity used when CacheOptions.MaxEntries is unset.
+	DefaultCacheMaxEntries = 1000
+	// DefaultCacheTTL is how long successful lookups are kept when CacheOptions.TTL is unset.
+	DefaultCacheTTL = 24 * time.Hour
+	// DefaultCacheNegativeTTL is how long not-found results are kept when
+	// CacheOptions.NegativeTTL is unset.
+	DefaultCacheNegativeTTL = 10 * time.Minute
+)
+
+// CacheOptions configures a caching wrapper. Each wrapper has its own options, so TTLs
+// can be chosen per provider (e.g. long for DXCC data, shorter for callbook records).
+type CacheOptions struct {
+	// MaxEntries caps the number of cached callsigns; the least recently used entry is
+	// evicted first.
+	MaxEntries int
+	// TTL is how long a successful lookup is served from the cache.
+	TTL time.Duration
+	// NegativeTTL is how long an errors.ErrNotFound result is served from the cache. It
+	// is usually shorter than TTL so newly issued callsigns show up quickly. A negative
+	// value disables negative caching.
+	NegativeTTL time.Duration
+	// Store, if set, is a persistent second level consulted on a memory miss and written
+	// on every cacheable result, so results survive restarts and are available offline.
+	// Store failures are treated as misses.
+	Store CacheStore
+	// Namespace prefixes keys written to Store so that several wrappers can share one
+	// store; it is typically the provider's service name. It also labels the wrapper's
+	// metrics.
+	Namespace string
+	// Metrics, if set, is told how every lookup was answered.
+	Metrics metrics.Recorder
+}
+
+// CacheStatus reports how a lookup was answered by a caching wrapper.
+type CacheStatus int
+
+const (
+	// CacheMiss means the wrapped provider was queried.
+	CacheMiss CacheStatus = iota
+	// CacheHit means a cached result was returned.
+	CacheHit
+	// CacheNegativeHit means a cached not-found result was returned.
+	CacheNegativeHit
+	// CacheStoreHit means the result was loaded from the persistent store.
+	CacheStoreHit
+)
+
+// metricsResult maps the status to its metrics label.
+func (s CacheStatus) metricsResult() metrics.CacheResult {
+	switch s {
+	case CacheHit:
+		return metrics.CacheHit
+	case CacheNegativeHit:
+		return metrics.CacheNegativeHit
+	case CacheStoreHit:
+		return metrics.CacheStoreHit
+	default:
+		return metrics.CacheMiss
+	}
+}
+
+// String returns a human-readable name for the status.
+func (s CacheStatus) String() string {
+	switch s {
+	case CacheHit:
+		return "hit"
+	case CacheNegativeHit:
+		return "negative-hit"
+	case CacheStoreHit:
+		return "store-hit"
+	default:
+		return "miss"
+	}
+}
+
+// CacheStats is a snapshot of a cache's counters.
+type CacheStats struct {
+	Hits         uint64
+	NegativeHits uint64
+	StoreHits    uint64
+	Misses       uint64
+	Evictions    uint64
+	Entries      int
+}
+
+// CachedProvider is a Provider that answers repeated lookups from an in-memory LRU
+// cache instead of the wrapped provider. Dated lookups (LookupAt) of a HistoricalProvider
+// bypass the cache and go straight to the wrapped provider.
+type CachedProvider struct {
+	provider Provider
+	cache    *lruCache[types.Country]
+}
+
+// NewCachedProvider wraps p with an in-memory TTL cache.
+func NewCachedProvider(p Provider, opts CacheOptions) *CachedProvider {
+	return &CachedProvider{provider: p, cache: newLRUCache[types.Country](opts)}
+}
+
+// Initialize initializes the wrapped provider.
+func (c *CachedProvider) Initialize() error {
+	const op errors.Op = "lookup.CachedProvider.Initialize"
+	if c.provider == nil {
+		return errors.New(op).Msg("wrapped provider has not been set")
+	}
+	return c.provider.Initialize()
+}
+
+// Lookup resolves a callsign using the default context.
+func (c *CachedProvider) Lookup(callsign string) (types.Country, error) {
+	return c.LookupWithContext(context.Background(), callsign)
+}
+
+// LookupWithContext resolves a callsign, consulting the cache first.
+func (c *CachedProvider) LookupWithContext(ctx context.Context, callsign string) (types.Country, error) {
+	country, _, err := c.LookupCached(ctx, callsign)
+	return country, err
+}
+
+// LookupCached behaves like LookupWithContext and also reports whether the result came
+// from the cache.
+func (c *CachedProvider) LookupCached(ctx context.Context, callsign string) (types.Country, CacheStatus, error) {
+	if c.provider == nil {
+		return types.Country{}, CacheMiss, errors.New("lookup.CachedProvider.LookupCached").Msg("wrapped provider has not been set")
+	}
+	return c.cache.lookup(ctx, callsign, c.provider.LookupWithContext)
+}
+
+// LookupAt resolves callsign as of at. If the wrapped provider is a HistoricalProvider
+// it is asked directly, bypassing the cache; otherwise this is LookupWithContext.
+func (c *CachedProvider) LookupAt(ctx context.Context, callsign string, at time.Time) (types.Country, error) {
+	country, _, err := c.lookupAt(ctx, callsign, at)
+	return country, err
+}
+
+func (c *CachedProvider) historical() bool {
+	return c.provider != nil && isHistorical(c.provider)
+}
+
+func (c *CachedProvider) lookupAt(ctx context.Context, callsign string, at time.Time) (types.Country, bool, error) {
+	if !c.historical() || at.IsZero() {
+		country, err := c.LookupWithContext(ctx, callsign)
+		return country, false, err
+	}
+	return LookupAt(ctx, c.provider, callsign, at)
+}
+
+// Invalidate removes a callsign from the cache and its store.
+func (c *CachedProvider) Invalidate(callsign string) { c.cache.remove(cacheKey(callsign)) }
+
+// Purge empties the in-memory cache; the store is left untouched.
+func (c *CachedProvider) Purge() { c.cache.purge() }
+
+// Stats returns a snapshot of the cache counters.
+func (c *CachedProvider) Stats() CacheStats { return c.cache.stats() }
+
+// CachedStationProvider is the StationProvider counterpart of CachedProvider.
+type CachedStationProvider struct {
+	provider StationProvider
+	cache    *lruCache[types.ContactedStation]
+}
+
+// NewCachedStationProvider wraps p with an in-memory TTL cache.
+func NewCachedStationProvider(p StationProvider, opts CacheOptions) *CachedStationProvider {
+	return &CachedStationProvider{provider: p, cache: newLRUCache[types.ContactedStation](opts)}
+}
+
+// Initialize initializes the wrapped provider.
+func (c *CachedStationProvider) Initialize() error {
+	const op errors.Op = "lookup.CachedStationProvider.Initialize"
+	if c.provider == nil {
+		return errors.New(op).Msg("wrapped provider has not been set")
+	}
+	return c.provider.Initialize()
+}
+
+// Lookup retrieves a station using the default context.
+func (c *CachedStationProvider) Lookup(callsign string) (types.ContactedStation, error) {
+	return c.LookupWithContext(context.Background(), callsign)
+}
+
+// LookupWithContext retrieves a station, consulting the cache first.
+func (c *CachedStationProvider) LookupWithContext(ctx context.Context, callsign string) (types.ContactedStation, error) {
+	station, _, err := c.LookupCached(ctx, callsign)
+	return station, err
+}
+
+// LookupCached behaves like LookupWithContext and also reports whether the result came
+// from the cache.
+func (c *CachedStationProvider) LookupCached(ctx context.Context, callsign string) (types.ContactedStation, CacheStatus, error) {
+	if c.provider == nil {
+		return types.ContactedStation{}, CacheMiss, errors.New("lookup.CachedStationProvider.LookupCached").Msg("wrapped provider has not been set")
+	}
+	return c.cache.lookup(ctx, callsign, c.provider.LookupWithContext)
+}
+
+// Invalidate removes a callsign from the cache and its store.
+func (c *CachedStationProvider) Invalidate(callsign string) { c.cache.remove(cacheKey(callsign)) }
+
+// Purge empties the in-memory cache; the store is left untouched.
+func (c *CachedStationProvider) Purge() { c.cache.purge() }
+
+// Stats returns a snapshot of the cache counters.
+func (c *CachedStationProvider) Stats() CacheStats { return c.cache.stats() }
+
+// cacheKey normalises a callsign (see NormalizeCallsign) so that trivially different
+// spellings such as DLØABC and DL0ABC share an entry. Input that does not normalise is
+// keyed on its trimmed, upper-case form and left for the provider to reject.
+func cacheKey(callsign string) string {
+	if norm, err := calls.Normalize(callsign); err == nil {
+		return norm
+	}
+	return strings.ToUpper(strings.TrimSpace(callsign))
+}
+
+type cacheEntry[V any] struct {
+	key     string
+	value   V
+	err     error // set for negative entries
+	expires time.Time
+}
+
+// lruCache is a size-bounded, TTL-aware LRU map shared by the caching wrappers.
+type lruCache[V any] struct {
+	opts CacheOptions
+	now  func() time.Time
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+	st    CacheStats
+}
+
+func newLRUCache[V any](opts CacheOptions) *lruCache[V] {
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = DefaultCacheMaxEntries
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultCacheTTL
+	}
+	if opts.NegativeTTL == 0 {
+		opts.NegativeTTL = DefaultCacheNegativeTTL
+	}
+	return &lruCache[V]{
+		opts:  opts,
+		now:   time.Now,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// lookup resolves callsign through the cache and reports the outcome to the metrics
+// recorder, if any.
+func (c *lruCache[V]) lookup(ctx context.Context, callsign string, fetch func(context.Context, string) (V, error)) (V, CacheStatus, error) {
+	value, status, err := c.resolve(ctx, callsign, fetch)
+	if c.opts.Metrics != nil {
+		name := c.opts.Namespace
+		if name == "" {
+			name = "cache"
+		}
+		c.opts.Metrics.CacheLookup(name, status.metricsResult())
+	}
+	return value, status, err
+}
+
+// resolve serves callsign from memory, then from the store and finally from fetch,
+// caching successful results and not-found errors. Other errors are never cached.
+func (c *lruCache[V]) resolve(ctx context.Context, callsign string, fetch func(context.Context, string) (V, error)) (V, CacheStatus, error) {
+	key := cacheKey(callsign)
+	if key != "" {
+		if e, ok := c.get(key); ok {
+			if e.err != nil {
+				c.count(func(st *CacheStats) { st.NegativeHits++ })
+				return e.value, CacheNegativeHit, e.err
+			}
+			c.count(func(st *CacheStats) { st.Hits++ })
+			return e.value, CacheHit, nil
+		}
+		if e, ok := c.load(key); ok {
+			if e.err != nil {
+				c.count(func(st *CacheStats) { st.NegativeHits++ })
+				return e.value, CacheNegativeHit, e.err
+			}
+			c.count(func(st *CacheStats) { st.StoreHits++ })
+			return e.value, CacheStoreHit, nil
+		}
+	}
+
+	c.count(func(st *CacheStats) { st.Misses++ })
+	value, err := fetch(ctx, callsign)
+	if key == "" {
+		return value, CacheMiss, err
+	}
+	switch {
+	case err == nil:
+		c.save(cacheEntry[V]{key: key, value: value, expires: c.now().Add(c.opts.TTL)})
+	case stderr.Is(err, errors.ErrNotFound) && c.opts.NegativeTTL > 0:
+		c.save(cacheEntry[V]{key: key, value: value, err: err, expires: c.now().Add(c.opts.NegativeTTL)})
+	}
+	return value, CacheMiss, err
+}
+
+// load reads key from the store into memory.
+func (c *lruCache[V]) load(key string) (cacheEntry[V], bool) {
+	if c.opts.Store == nil {
+		return cacheEntry[V]{}, false
+	}
+	se, ok, err := c.opts.Store.Get(c.storeKey(key))
+	if err != nil || !ok || !c.now().Before(se.Expires) {
+		return cacheEntry[V]{}, false
+	}
+
+	e := cacheEntry[V]{key: key, expires: se.Expires}
+	if se.NotFound {
+		e.err = errors.New("lookup.cache.load").Err(errors.ErrNotFound).Msgf("%s was not found (cached)", key)
+	} else if json.Unmarshal(se.Value, &e.value) != nil {
+		return cacheEntry[V]{}, false
+	}
+	c.set(e)
+	return e, true
+}
+
+// save writes e to memory and, best effort, to the store.
+func (c *lruCache[V]) save(e cacheEntry[V]) {
+	c.set(e)
+	if c.opts.Store == nil {
+		return
+	}
+	se := StoredEntry{NotFound: e.err != nil, Expires: e.expires}
+	if e.err == nil {
+		value, err := json.Marshal(e.value)
+		if err != nil {
+			return
+		}
+		se.Value = value
+	}
+	_ = c.opts.Store.Put(c.storeKey(e.key), se)
+}
+
+func (c *lruCache[V]) storeKey(key string) string {
+	if c.opts.Namespace == "" {
+		return key
+	}
+	return c.opts.Namespace + ":" + key
+}
+
+func (c *lruCache[V]) count(fn func(st *CacheStats)) {
+	c.mu.Lock()
+	fn(&c.st)
+	c.mu.Unlock()
+}
+
+func (c *lruCache[V]) get(key string) (cacheEntry[V], bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return cacheEntry[V]{}, false
+	}
+	e := el.Value.(*cacheEntry[V])
+	if !c.now().Before(e.expires) {
+		c.ll.Remove(el)
+		delete(c.items, key)
+		return cacheEntry[V]{}, false
+	}
+
+	c.ll.MoveToFront(el)
+	return *e, true
+}
+
+func (c *lruCache[V]) set(e cacheEntry[V]) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[e.key]; ok {
+		el.Value = &e
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[e.key] = c.ll.PushFront(&e)
+	for c.ll.Len() > c.opts.MaxEntries {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry[V]).key)
+		c.st.Evictions++
+	}
+}
+
+func (c *lruCache[V]) remove(key string) {
+	c.mu.Lock()
+	if el, ok := c.items[key]; ok {
+		c.ll.Remove(el)
+		delete(c.items, key)
+	}
+	c.mu.Unlock()
+
+	if c.opts.Store != nil {
+		_ = c.opts.Store.Delete(c.storeKey(key))
+	}
+}
+
+func (c *lruCache[V]) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+}
+
+func (c *lruCache[V]) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st := c.st
+	st.Entries = c.ll.Len()
+	return st
+}
@@ -0,0 +1,194 @@
+package lookup
+
+import (
+	"context"
+	stderrors "errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	smerrors "github.com/Station-Manager/errors"
+	"github.com/Station-Manager/lookup/metrics"
+	"github.com/Station-Manager/types"
+)
+
+// funcProvider adapts a function to Provider.
+type funcProvider func(ctx context.Context, callsign string) (types.Country, error)
+
+func (funcProvider) Initialize() error { return nil }
+func (f funcProvider) Lookup(callsign string) (types.Country, error) {
+	return f(context.Background(), callsign)
+}
+func (f funcProvider) LookupWithContext(ctx context.Context, callsign string) (types.Country, error) {
+	return f(ctx, callsign)
+}
+
+// funcStationProvider adapts a function to StationProvider.
+type funcStationProvider func(ctx context.Context, callsign string) (types.ContactedStation, error)
+
+func (funcStationProvider) Initialize() error { return nil }
+func (f funcStationProvider) Lookup(callsign string) (types.ContactedStation, error) {
+	return f(context.Background(), callsign)
+}
+func (f funcStationProvider) LookupWithContext(ctx context.Context, callsign string) (types.ContactedStation, error) {
+	return f(ctx, callsign)
+}
+
+func TestCachedProvider_HitAndExpiry(t *testing.T) {
+	var calls atomic.Int32
+	p := NewCachedProvider(funcProvider(func(_ context.Context, callsign string) (types.Country, error) {
+		calls.Add(1)
+		return types.Country{Name: "TestLand"}, nil
+	}), CacheOptions{TTL: time.Minute})
+	now := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	p.cache.now = func() time.Time { return now }
+
+	if _, status, _ := p.LookupCached(context.Background(), "k1abc"); status != CacheMiss {
+		t.Fatalf("expected miss, got %v", status)
+	}
+	country, status, err := p.LookupCached(context.Background(), " K1ABC ")
+	if err != nil || status != CacheHit || country.Name != "TestLand" {
+		t.Fatalf("expected cached TestLand, got %#v %v %v", country, status, err)
+	}
+	if _, status, _ = p.LookupCached(context.Background(), "Ｋ１ＡＢＣ"); status != CacheHit {
+		t.Fatalf("expected a full-width spelling to share the entry, got %v", status)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, status, _ := p.LookupCached(context.Background(), "K1ABC"); status != CacheMiss {
+		t.Fatalf("expected miss after expiry, got %v", status)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("expected 2 upstream calls, got %d", got)
+	}
+}
+
+func TestCachedProvider_NegativeCaching(t *testing.T) {
+	var calls atomic.Int32
+	p := NewCachedProvider(funcProvider(func(_ context.Context, _ string) (types.Country, error) {
+		calls.Add(1)
+		return types.Country{}, smerrors.New("test").Err(smerrors.ErrNotFound).Msg("not found")
+	}), CacheOptions{TTL: time.Hour, NegativeTTL: time.Minute})
+	now := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	p.cache.now = func() time.Time { return now }
+
+	_, _, _ = p.LookupCached(context.Background(), "QQ1ABC")
+	_, status, err := p.LookupCached(context.Background(), "QQ1ABC")
+	if status != CacheNegativeHit || !stderrors.Is(err, smerrors.ErrNotFound) {
+		t.Fatalf("expected negative hit with ErrNotFound, got %v %v", status, err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	_, _, _ = p.LookupCached(context.Background(), "QQ1ABC")
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("expected negative entry to expire after NegativeTTL, got %d upstream calls", got)
+	}
+}
+
+func TestCachedProvider_DoesNotCacheOtherErrors(t *testing.T) {
+	var calls atomic.Int32
+	p := NewCachedProvider(funcProvider(func(_ context.Context, _ string) (types.Country, error) {
+		calls.Add(1)
+		return types.Country{}, stderrors.New("boom")
+	}), CacheOptions{})
+
+	_, _ = p.Lookup("K1ABC")
+	_, _ = p.Lookup("K1ABC")
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("expected errors to bypass the cache, got %d upstream calls", got)
+	}
+}
+
+func TestCachedProvider_LRUEviction(t *testing.T) {
+	p := NewCachedProvider(funcProvider(func(_ context.Context, callsign string) (types.Country, error) {
+		return types.Country{Name: callsign}, nil
+	}), CacheOptions{MaxEntries: 2})
+
+	_, _ = p.Lookup("A1A")
+	_, _ = p.Lookup("B1B")
+	_, _ = p.Lookup("A1A") // A1A is now most recently used
+	_, _ = p.Lookup("C1C") // evicts B1B
+
+	if _, status, _ := p.LookupCached(context.Background(), "A1A"); status != CacheHit {
+		t.Fatalf("expected A1A to survive eviction, got %v", status)
+	}
+	if _, status, _ := p.LookupCached(context.Background(), "B1B"); status != CacheMiss {
+		t.Fatalf("expected B1B to be evicted, got %v", status)
+	}
+	if st := p.Stats(); st.Evictions < 1 || st.Entries != 2 {
+		t.Fatalf("unexpected stats: %#v", st)
+	}
+}
+
+func TestCachedStationProvider_Hit(t *testing.T) {
+	var calls atomic.Int32
+	p := NewCachedStationProvider(funcStationProvider(func(_ context.Context, callsign string) (types.ContactedStation, error) {
+		calls.Add(1)
+		return types.ContactedStation{Call: callsign}, nil
+	}), CacheOptions{})
+
+	_, _ = p.Lookup("AA7BQ")
+	station, status, err := p.LookupCached(context.Background(), "aa7bq")
+	if err != nil || status != CacheHit || station.Call != "AA7BQ" {
+		t.Fatalf("expected cached station, got %#v %v %v", station, status, err)
+	}
+
+	p.Invalidate("AA7BQ")
+	_, _ = p.Lookup("AA7BQ")
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("expected invalidation to force a refetch, got %d upstream calls", got)
+	}
+}
+
+func TestCachedProvider_RecordsMetrics(t *testing.T) {
+	reg := metrics.NewRegistry()
+	p := NewCachedProvider(funcProvider(func(context.Context, string) (types.Country, error) {
+		return types.Country{Name: "TestLand"}, nil
+	}), CacheOptions{Namespace: "hamnut", Metrics: reg})
+
+	_, _ = p.Lookup("K1ABC")
+	_, _ = p.Lookup("K1ABC")
+
+	var b strings.Builder
+	_ = reg.WritePrometheus(&b)
+	for _, want := range []string{
+		`lookup_cache_requests_total{cache="hamnut",result="hit"} 1`,
+		`lookup_cache_requests_total{cache="hamnut",result="miss"} 1`,
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Fatalf("metrics are missing %q:\n%s", want, b.String())
+		}
+	}
+}
+
+func TestCachedProvider_LookupAtBypassesCache(t *testing.T) {
+	at := time.Date(1993, time.March, 14, 0, 0, 0, 0, time.UTC)
+	p := &datedProvider{}
+	c := NewCachedProvider(p, CacheOptions{})
+
+	// Prime the cache with the current assignment; the dated lookup must not see it.
+	if _, err := c.LookupWithContext(context.Background(), "K1ABC"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	country, periodCorrect, err := LookupAt(context.Background(), c, "K1ABC", at)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !periodCorrect || country.Name != "historical" || !p.gotAt.Equal(at) {
+		t.Fatalf("expected dated lookup, got %#v (periodCorrect=%v)", country, periodCorrect)
+	}
+}
+
+func TestCachedProvider_LookupAtOfOnlineProviderFallsBack(t *testing.T) {
+	c := NewCachedProvider(currentOnlyProvider{}, CacheOptions{})
+
+	country, periodCorrect, err := LookupAt(context.Background(), c, "K1ABC", time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if periodCorrect || country.Name != "current" {
+		t.Fatalf("expected current-assignment fallback, got %#v (periodCorrect=%v)", country, periodCorrect)
+	}
+}
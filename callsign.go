@@ -0,0 +1,47 @@
+package lookup
+
+import "github.com/Station-Manager/lookup/internal/calls"
+
+// Callsign is a callsign split into home call, operating prefix and suffix, so that
+// portable, mobile and reciprocal forms (VP2E/K1ABC, K1ABC/VE3, DL1XX/P, G4ABC/MM) can be
+// resolved to the entity the station is actually operating from. The bundled providers
+// parse every callsign this way before querying.
+type Callsign = calls.Callsign
+
+// Indicator classifies the suffix appended to a callsign.
+type Indicator = calls.Indicator
+
+const (
+	IndicatorNone         = calls.IndicatorNone
+	IndicatorPortable     = calls.IndicatorPortable
+	IndicatorMobile       = calls.IndicatorMobile
+	IndicatorMaritime     = calls.IndicatorMaritime
+	IndicatorAeronautical = calls.IndicatorAeronautical
+	IndicatorQRP          = calls.IndicatorQRP
+	IndicatorAreaDigit    = calls.IndicatorAreaDigit
+	IndicatorOther        = calls.IndicatorOther
+	IndicatorMountAthos   = calls.IndicatorMountAthos
+)
+
+// ParseCallsign splits a callsign into its structural parts. See Callsign.
+func ParseCallsign(s string) (Callsign, error) {
+	return calls.Parse(s)
+}
+
+// CallsignError reports a callsign rejected before any lookup was made. It matches
+// ErrInvalidCallsign via errors.Is.
+type CallsignError = calls.CallsignError
+
+// NormalizeCallsign upper-cases a callsign and repairs common input artefacts: full-width
+// characters, slashed zeros (Ø), '\' separators and spaces around separators. Input that
+// still contains spaces or other characters is rejected with a *CallsignError.
+func NormalizeCallsign(s string) (string, error) {
+	return calls.Normalize(s)
+}
+
+// ValidateCallsign normalizes and parses a callsign and checks it against the ITU
+// callsign structure, rejecting input such as "TEST" or "12345" with a *CallsignError.
+// The HTTP providers apply it to every callsign before making a request.
+func ValidateCallsign(s string) (Callsign, error) {
+	return calls.Validate(s)
+}
@@ -0,0 +1,197 @@
+package lookup
+
+import (
+	"context"
+	stderr "errors"
+	"time"
+
+	"github.com/Station-Manager/errors"
+	"github.com/Station-Manager/lookup/metrics"
+	"github.com/Station-Manager/types"
+)
+
+// ChainLink is a named provider in a Chain.
+type ChainLink struct {
+	Name     string
+	Provider Provider
+}
+
+// ChainAttempt records a provider the chain tried and moved past.
+type ChainAttempt struct {
+	Provider string
+	Err      error
+}
+
+// ChainResult is the answer of a Chain together with the provider that gave it.
+type ChainResult struct {
+	Country types.Country
+	// Provider is the name of the link that answered; empty if every provider is disabled.
+	Provider string
+	// Skipped lists the providers tried before Provider, in order, with the reason each
+	// was passed over.
+	Skipped []ChainAttempt
+}
+
+// Chain is a Provider that tries an ordered list of providers, moving on when one
+// fails transiently (network error, timeout, 5xx, rate limit), is disabled or could not
+// be initialized. A definitive answer, including not-found, ends the chain.
+type Chain struct {
+	links    []ChainLink
+	initErrs []error
+
+	// Failover decides whether an error moves the chain on to the next provider. It
+	// defaults to IsTransient.
+	Failover func(err error) bool
+
+	// Metrics, if set, records every chained lookup and every provider moved past.
+	Metrics metrics.Recorder
+	// Name labels the chain's metrics; it defaults to "chain".
+	Name string
+}
+
+// NewChain returns a chain that consults links in the given order.
+func NewChain(links ...ChainLink) *Chain {
+	return &Chain{links: links, Failover: IsTransient}
+}
+
+// Initialize initializes every provider in the chain. Providers that fail to initialize
+// are skipped by later lookups; an error is returned only if none could be initialized.
+func (c *Chain) Initialize() error {
+	const op errors.Op = "lookup.Chain.Initialize"
+	if len(c.links) == 0 {
+		return errors.New(op).Msg("chain has no providers")
+	}
+
+	c.initErrs = make([]error, len(c.links))
+	failed := 0
+	for i, link := range c.links {
+		if link.Provider == nil {
+			c.initErrs[i] = errors.New(op).Msgf("provider %q has not been set", link.Name)
+		} else {
+			c.initErrs[i] = link.Provider.Initialize()
+		}
+		if c.initErrs[i] != nil {
+			failed++
+		}
+	}
+	if failed == len(c.links) {
+		return errors.New(op).Err(stderr.Join(c.initErrs...)).Msg("no provider in the chain could be initialized")
+	}
+	return nil
+}
+
+// Lookup resolves a callsign using the default context.
+func (c *Chain) Lookup(callsign string) (types.Country, error) {
+	return c.LookupWithContext(context.Background(), callsign)
+}
+
+// LookupWithContext resolves a callsign using the first provider able to answer.
+func (c *Chain) LookupWithContext(ctx context.Context, callsign string) (types.Country, error) {
+	result, err := c.LookupChained(ctx, callsign)
+	return result.Country, err
+}
+
+// LookupChained behaves like LookupWithContext and also reports which provider answered
+// and which were skipped.
+func (c *Chain) LookupChained(ctx context.Context, callsign string) (ChainResult, error) {
+	return c.run(ctx, func(ctx context.Context, p Provider) (types.Country, error) {
+		return p.LookupWithContext(ctx, callsign)
+	})
+}
+
+// LookupAt resolves callsign as of at using the first provider able to answer. Each
+// provider is asked through lookup.LookupAt, so a HistoricalProvider answers for that
+// date and the others with their current assignment.
+func (c *Chain) LookupAt(ctx context.Context, callsign string, at time.Time) (types.Country, error) {
+	country, _, err := c.lookupAt(ctx, callsign, at)
+	return country, err
+}
+
+func (c *Chain) historical() bool {
+	for _, link := range c.links {
+		if link.Provider != nil && isHistorical(link.Provider) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Chain) lookupAt(ctx context.Context, callsign string, at time.Time) (types.Country, bool, error) {
+	var periodCorrect bool
+	result, err := c.run(ctx, func(ctx context.Context, p Provider) (types.Country, error) {
+		country, ok, err := LookupAt(ctx, p, callsign, at)
+		periodCorrect = ok
+		return country, err
+	})
+	return result.Country, periodCorrect && err == nil, err
+}
+
+// run performs a chained lookup with resolve and records it to the metrics recorder.
+func (c *Chain) run(ctx context.Context, resolve func(context.Context, Provider) (types.Country, error)) (ChainResult, error) {
+	name := c.Name
+	if name == "" {
+		name = "chain"
+	}
+	done := metrics.Start(c.Metrics, name)
+	result, err := c.lookupChained(ctx, resolve)
+	done(err)
+	if c.Metrics != nil {
+		for _, skipped := range result.Skipped {
+			c.Metrics.Failover(name, skipped.Provider, metrics.Classify(skipped.Err))
+		}
+	}
+	return result, err
+}
+
+func (c *Chain) lookupChained(ctx context.Context, resolve func(context.Context, Provider) (types.Country, error)) (ChainResult, error) {
+	const op errors.Op = "lookup.Chain.LookupChained"
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	failover := c.Failover
+	if failover == nil {
+		failover = IsTransient
+	}
+
+	var result ChainResult
+	var lastErr error
+	for i, link := range c.links {
+		if i < len(c.initErrs) && c.initErrs[i] != nil {
+			result.Skipped = append(result.Skipped, ChainAttempt{Provider: link.Name, Err: c.initErrs[i]})
+			lastErr = c.initErrs[i]
+			continue
+		}
+		if link.Provider == nil {
+			continue
+		}
+
+		country, err := resolve(ctx, link.Provider)
+		switch {
+		case stderr.Is(err, ErrDisabled):
+			result.Skipped = append(result.Skipped, ChainAttempt{Provider: link.Name, Err: err})
+			continue
+		case err == nil:
+			result.Country = country
+			result.Provider = link.Name
+			return result, nil
+		}
+
+		// The caller giving up ends the chain; a provider timing out does not.
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return result, errors.New(op).Err(err).Msg("lookup cancelled")
+		}
+		if !failover(err) {
+			result.Provider = link.Name
+			return result, err
+		}
+		result.Skipped = append(result.Skipped, ChainAttempt{Provider: link.Name, Err: err})
+		lastErr = err
+	}
+
+	if lastErr == nil {
+		// Every provider is disabled: answer the way a single disabled provider does.
+		result.Country = types.Country{Name: "Unknown"}
+		return result, errors.New(op).Err(ErrDisabled).Msg("every provider in the chain is disabled")
+	}
+	return result, errors.New(op).Err(lastErr).Msg("every provider in the chain failed")
+}
@@ -0,0 +1,169 @@
+package lookup
+
+import (
+	"context"
+	stderrors "errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	smerrors "github.com/Station-Manager/errors"
+	"github.com/Station-Manager/lookup/metrics"
+	"github.com/Station-Manager/types"
+)
+
+func countryProvider(name string) funcProvider {
+	return func(context.Context, string) (types.Country, error) {
+		return types.Country{Name: name}, nil
+	}
+}
+
+func failingProvider(err error) funcProvider {
+	return func(context.Context, string) (types.Country, error) {
+		return types.Country{}, err
+	}
+}
+
+func TestChain_FailsOverOnTransientErrors(t *testing.T) {
+	netErr := smerrors.New("test").Err(&net.OpError{Op: "dial", Net: "tcp", Err: stderrors.New("connection refused")}).Msg("dial failed")
+	serverErr := smerrors.New("test").Err(&StatusError{StatusCode: 503}).Msg("unavailable")
+
+	c := NewChain(
+		ChainLink{Name: "hamnut", Provider: failingProvider(netErr)},
+		ChainLink{Name: "qrz", Provider: failingProvider(serverErr)},
+		ChainLink{Name: "offline", Provider: countryProvider("TestLand")},
+	)
+
+	result, err := c.LookupChained(context.Background(), "K1ABC")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Provider != "offline" || result.Country.Name != "TestLand" {
+		t.Fatalf("expected offline to answer, got %#v", result)
+	}
+	if len(result.Skipped) != 2 || result.Skipped[0].Provider != "hamnut" || result.Skipped[1].Provider != "qrz" {
+		t.Fatalf("unexpected skipped providers: %#v", result.Skipped)
+	}
+}
+
+func TestChain_StopsOnNotFound(t *testing.T) {
+	var consulted bool
+	c := NewChain(
+		ChainLink{Name: "hamnut", Provider: failingProvider(smerrors.New("test").Err(smerrors.ErrNotFound).Msg("not found"))},
+		ChainLink{Name: "offline", Provider: funcProvider(func(context.Context, string) (types.Country, error) {
+			consulted = true
+			return types.Country{Name: "TestLand"}, nil
+		})},
+	)
+
+	result, err := c.LookupChained(context.Background(), "QQ1ABC")
+	if !stderrors.Is(err, smerrors.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if consulted || result.Provider != "hamnut" {
+		t.Fatalf("expected the chain to stop at hamnut, got %#v (consulted=%v)", result, consulted)
+	}
+}
+
+func TestChain_SkipsDisabledProviders(t *testing.T) {
+	c := NewChain(
+		ChainLink{Name: "hamnut", Provider: failingProvider(smerrors.New("test").Err(ErrDisabled).Msg("disabled"))},
+		ChainLink{Name: "offline", Provider: countryProvider("TestLand")},
+	)
+
+	result, err := c.LookupChained(context.Background(), "K1ABC")
+	if err != nil || result.Provider != "offline" {
+		t.Fatalf("expected disabled provider to be skipped, got %#v %v", result, err)
+	}
+}
+
+func TestChain_AcceptsEntityNamedUnknown(t *testing.T) {
+	c := NewChain(
+		ChainLink{Name: "a", Provider: countryProvider("Unknown")},
+		ChainLink{Name: "b", Provider: countryProvider("TestLand")},
+	)
+
+	result, err := c.LookupChained(context.Background(), "K1ABC")
+	if err != nil || result.Provider != "a" || result.Country.Name != "Unknown" {
+		t.Fatalf("expected the first provider's answer, got %#v %v", result, err)
+	}
+}
+
+func TestChain_AllFail(t *testing.T) {
+	serverErr := smerrors.New("test").Err(&StatusError{StatusCode: 500}).Msg("boom")
+	c := NewChain(
+		ChainLink{Name: "a", Provider: failingProvider(serverErr)},
+		ChainLink{Name: "b", Provider: failingProvider(serverErr)},
+	)
+
+	_, err := c.Lookup("K1ABC")
+	var se *StatusError
+	if !stderrors.As(err, &se) || se.StatusCode != 500 {
+		t.Fatalf("expected the last StatusError to be reported, got %v", err)
+	}
+}
+
+func TestChain_RecordsMetrics(t *testing.T) {
+	reg := metrics.NewRegistry()
+	c := NewChain(
+		ChainLink{Name: "qrz", Provider: failingProvider(smerrors.New("test").Err(&StatusError{StatusCode: 503}).Msg("unavailable"))},
+		ChainLink{Name: "offline", Provider: countryProvider("TestLand")},
+	)
+	c.Metrics = reg
+
+	if _, err := c.Lookup("K1ABC"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var b strings.Builder
+	_ = reg.WritePrometheus(&b)
+	for _, want := range []string{
+		`lookup_requests_total{provider="chain"} 1`,
+		`lookup_chain_failovers_total{chain="chain",provider="qrz",class="unavailable"} 1`,
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Fatalf("metrics are missing %q:\n%s", want, b.String())
+		}
+	}
+}
+
+func TestChain_LookupAtFailsOverToHistoricalProvider(t *testing.T) {
+	at := time.Date(1993, time.March, 14, 0, 0, 0, 0, time.UTC)
+	netErr := smerrors.New("test").Err(&net.OpError{Op: "dial", Net: "tcp", Err: stderrors.New("connection refused")}).Msg("dial failed")
+	p := &datedProvider{}
+	c := NewChain(
+		ChainLink{Name: "hamnut", Provider: failingProvider(netErr)},
+		ChainLink{Name: "clublog", Provider: p},
+	)
+
+	country, periodCorrect, err := LookupAt(context.Background(), c, "K1ABC", at)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !periodCorrect || country.Name != "historical" || !p.gotAt.Equal(at) {
+		t.Fatalf("expected dated lookup, got %#v (periodCorrect=%v)", country, periodCorrect)
+	}
+
+	// A cache around the chain passes the date through as well.
+	cached := NewCachedProvider(c, CacheOptions{})
+	if _, periodCorrect, err = LookupAt(context.Background(), cached, "K1ABC", at); err != nil || !periodCorrect {
+		t.Fatalf("expected dated lookup through the cache, got periodCorrect=%v err=%v", periodCorrect, err)
+	}
+}
+
+func TestChain_LookupAtReportsAnsweringProvider(t *testing.T) {
+	at := time.Date(1993, time.March, 14, 0, 0, 0, 0, time.UTC)
+	c := NewChain(
+		ChainLink{Name: "hamnut", Provider: currentOnlyProvider{}},
+		ChainLink{Name: "clublog", Provider: &datedProvider{}},
+	)
+
+	country, periodCorrect, err := LookupAt(context.Background(), c, "K1ABC", at)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if periodCorrect || country.Name != "current" {
+		t.Fatalf("expected the first provider's current answer, got %#v (periodCorrect=%v)", country, periodCorrect)
+	}
+}
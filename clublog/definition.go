@@ -0,0 +1,110 @@
+package clublog
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// ctyFile models the root element of Club Log's cty.xml.
+type ctyFile struct {
+	XMLName           xml.Name           `xml:"clublog"`
+	Date              string             `xml:"date,attr"`
+	Entities          []xmlEntity        `xml:"entities>entity"`
+	Exceptions        []xmlException     `xml:"exceptions>exception"`
+	Prefixes          []xmlException     `xml:"prefixes>prefix"`
+	InvalidOperations []xmlInvalid       `xml:"invalid_operations>invalid"`
+	ZoneExceptions    []xmlZoneException `xml:"zone_exceptions>zone_exception"`
+}
+
+type xmlEntity struct {
+	Adif    int     `xml:"adif"`
+	Name    string  `xml:"name"`
+	Prefix  string  `xml:"prefix"`
+	Deleted bool    `xml:"deleted"`
+	Cqz     int     `xml:"cqz"`
+	Cont    string  `xml:"cont"`
+	Long    float64 `xml:"long"`
+	Lat     float64 `xml:"lat"`
+	Start   string  `xml:"start"`
+	End     string  `xml:"end"`
+}
+
+// xmlException models both exception and prefix records, which share a layout.
+type xmlException struct {
+	Call   string  `xml:"call"`
+	Entity string  `xml:"entity"`
+	Adif   int     `xml:"adif"`
+	Cqz    int     `xml:"cqz"`
+	Cont   string  `xml:"cont"`
+	Long   float64 `xml:"long"`
+	Lat    float64 `xml:"lat"`
+	Start  string  `xml:"start"`
+	End    string  `xml:"end"`
+}
+
+type xmlInvalid struct {
+	Call  string `xml:"call"`
+	Start string `xml:"start"`
+	End   string `xml:"end"`
+}
+
+type xmlZoneException struct {
+	Call  string `xml:"call"`
+	Zone  int    `xml:"zone"`
+	Start string `xml:"start"`
+	End   string `xml:"end"`
+}
+
+// Validity is the period during which a record applies. A zero Start or End leaves that
+// side of the range open.
+type Validity struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Contains reports whether t falls within the validity period (inclusive).
+func (v Validity) Contains(t time.Time) bool {
+	if !v.Start.IsZero() && t.Before(v.Start) {
+		return false
+	}
+	if !v.End.IsZero() && t.After(v.End) {
+		return false
+	}
+	return true
+}
+
+// Entity is a DXCC entity from the entities section of cty.xml.
+type Entity struct {
+	ADIF      int
+	Name      string
+	Prefix    string
+	Deleted   bool
+	CQZone    int
+	Continent string
+	Lat       float64
+	Lon       float64
+	Validity  Validity
+}
+
+// Record is a callsign exception or prefix assignment with its validity period.
+type Record struct {
+	Call      string
+	ADIF      int
+	Name      string
+	CQZone    int
+	Continent string
+	Lat       float64
+	Lon       float64
+	Validity  Validity
+}
+
+// Match is the result of resolving a callsign against a Dataset.
+type Match struct {
+	Entity *Entity
+	// Record is the exception or prefix record that matched.
+	Record Record
+	// Exact is true when the callsign matched an exception rather than a prefix.
+	Exact bool
+	// CQZone is the record's zone, or the zone exception's zone where one applies.
+	CQZone int
+}
@@ -0,0 +1,215 @@
+package clublog
+
+import (
+	"encoding/xml"
+	stderr "errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Station-Manager/errors"
+)
+
+// ErrInvalidOperation is returned when Club Log lists the callsign as an invalid
+// operation (e.g. a pirate or unlicensed activity) at the requested time.
+var ErrInvalidOperation = stderr.New("callsign is listed as an invalid operation by Club Log")
+
+// Dataset is a parsed Club Log cty.xml file indexed for resolution.
+type Dataset struct {
+	// Date is the generation date reported by Club Log.
+	Date time.Time
+
+	entities       map[int]*Entity
+	exceptions     map[string][]Record
+	prefixes       map[string][]Record
+	invalid        map[string][]Validity
+	zoneExceptions map[string][]zoneException
+	maxPrefixLen   int
+}
+
+type zoneException struct {
+	zone     int
+	validity Validity
+}
+
+// ParseCTYXML parses Club Log's cty.xml.
+func ParseCTYXML(r io.Reader) (*Dataset, error) {
+	const op errors.Op = "clublog.ParseCTYXML"
+
+	var f ctyFile
+	if err := xml.NewDecoder(r).Decode(&f); err != nil {
+		return nil, errors.New(op).Err(err).Msg("decoding Club Log cty.xml")
+	}
+	if len(f.Entities) == 0 {
+		return nil, errors.New(op).Msg("Club Log cty.xml contains no entities")
+	}
+
+	ds := &Dataset{
+		entities:       make(map[int]*Entity, len(f.Entities)),
+		exceptions:     make(map[string][]Record, len(f.Exceptions)),
+		prefixes:       make(map[string][]Record, len(f.Prefixes)),
+		invalid:        make(map[string][]Validity, len(f.InvalidOperations)),
+		zoneExceptions: make(map[string][]zoneException, len(f.ZoneExceptions)),
+	}
+	ds.Date, _ = parseTime(f.Date)
+
+	for _, e := range f.Entities {
+		v, err := parseValidity(e.Start, e.End)
+		if err != nil {
+			return nil, errors.New(op).Err(err).Msgf("invalid dates for entity %d", e.Adif)
+		}
+		ds.entities[e.Adif] = &Entity{
+			ADIF:      e.Adif,
+			Name:      strings.TrimSpace(e.Name),
+			Prefix:    strings.ToUpper(strings.TrimSpace(e.Prefix)),
+			Deleted:   e.Deleted,
+			CQZone:    e.Cqz,
+			Continent: strings.ToUpper(strings.TrimSpace(e.Cont)),
+			Lat:       e.Lat,
+			Lon:       e.Long,
+			Validity:  v,
+		}
+	}
+
+	for _, x := range f.Exceptions {
+		rec, err := newRecord(x)
+		if err != nil {
+			return nil, errors.New(op).Err(err).Msgf("invalid exception for %q", x.Call)
+		}
+		ds.exceptions[rec.Call] = append(ds.exceptions[rec.Call], rec)
+	}
+
+	for _, x := range f.Prefixes {
+		rec, err := newRecord(x)
+		if err != nil {
+			return nil, errors.New(op).Err(err).Msgf("invalid prefix %q", x.Call)
+		}
+		ds.prefixes[rec.Call] = append(ds.prefixes[rec.Call], rec)
+		if len(rec.Call) > ds.maxPrefixLen {
+			ds.maxPrefixLen = len(rec.Call)
+		}
+	}
+
+	for _, x := range f.InvalidOperations {
+		v, err := parseValidity(x.Start, x.End)
+		if err != nil {
+			return nil, errors.New(op).Err(err).Msgf("invalid dates for invalid operation %q", x.Call)
+		}
+		call := normalizeCall(x.Call)
+		ds.invalid[call] = append(ds.invalid[call], v)
+	}
+
+	for _, x := range f.ZoneExceptions {
+		v, err := parseValidity(x.Start, x.End)
+		if err != nil {
+			return nil, errors.New(op).Err(err).Msgf("invalid dates for zone exception %q", x.Call)
+		}
+		call := normalizeCall(x.Call)
+		ds.zoneExceptions[call] = append(ds.zoneExceptions[call], zoneException{zone: x.Zone, validity: v})
+	}
+
+	return ds, nil
+}
+
+// Entity returns the entity with the given ADIF number.
+func (ds *Dataset) Entity(adif int) (*Entity, bool) {
+	e, ok := ds.entities[adif]
+	return e, ok
+}
+
+// Resolve resolves an upper-case callsign as of time at, following Club Log's order of
+// precedence: invalid operations, then callsign exceptions, then the longest prefix
+// valid at that time. Zone exceptions override the CQ zone of whatever matched.
+// errors.ErrNotFound is returned when nothing matches.
+func (ds *Dataset) Resolve(call string, at time.Time) (*Match, error) {
+	const op errors.Op = "clublog.Dataset.Resolve"
+
+	for _, v := range ds.invalid[call] {
+		if v.Contains(at) {
+			return nil, errors.New(op).Err(ErrInvalidOperation).Msgf("%s is listed as an invalid operation by Club Log", call)
+		}
+	}
+
+	var m *Match
+	if rec, ok := firstValid(ds.exceptions[call], at); ok {
+		m = &Match{Record: rec, Exact: true}
+	} else {
+		n := len(call)
+		if n > ds.maxPrefixLen {
+			n = ds.maxPrefixLen
+		}
+		for ; n > 0 && m == nil; n-- {
+			if rec, ok := firstValid(ds.prefixes[call[:n]], at); ok {
+				m = &Match{Record: rec}
+			}
+		}
+	}
+	if m == nil {
+		return nil, errors.New(op).Err(errors.ErrNotFound).Msgf("no Club Log entity for %s", call)
+	}
+
+	m.Entity = ds.entities[m.Record.ADIF]
+	m.CQZone = m.Record.CQZone
+	for _, z := range ds.zoneExceptions[call] {
+		if z.validity.Contains(at) {
+			m.CQZone = z.zone
+			break
+		}
+	}
+
+	return m, nil
+}
+
+// firstValid returns the first record valid at time at.
+func firstValid(records []Record, at time.Time) (Record, bool) {
+	for _, rec := range records {
+		if rec.Validity.Contains(at) {
+			return rec, true
+		}
+	}
+	return Record{}, false
+}
+
+func newRecord(x xmlException) (Record, error) {
+	v, err := parseValidity(x.Start, x.End)
+	if err != nil {
+		return Record{}, err
+	}
+	return Record{
+		Call:      normalizeCall(x.Call),
+		ADIF:      x.Adif,
+		Name:      strings.TrimSpace(x.Entity),
+		CQZone:    x.Cqz,
+		Continent: strings.ToUpper(strings.TrimSpace(x.Cont)),
+		Lat:       x.Lat,
+		Lon:       x.Long,
+		Validity:  v,
+	}, nil
+}
+
+func normalizeCall(call string) string {
+	return strings.ToUpper(strings.TrimSpace(call))
+}
+
+func parseValidity(start, end string) (Validity, error) {
+	var (
+		v   Validity
+		err error
+	)
+	if v.Start, err = parseTime(start); err != nil {
+		return v, err
+	}
+	if v.End, err = parseTime(end); err != nil {
+		return v, err
+	}
+	return v, nil
+}
+
+// parseTime parses Club Log's RFC 3339 timestamps; an empty value yields the zero time.
+func parseTime(v string) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
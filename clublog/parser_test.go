@@ -0,0 +1,107 @@
+package clublog
+
+import (
+	stderrors "errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	smerrors "github.com/Station-Manager/errors"
+)
+
+func loadTestDataset(t *testing.T) *Dataset {
+	t.Helper()
+	f, err := os.Open("testdata/cty.xml")
+	if err != nil {
+		t.Fatalf("opening test data: %v", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	ds, err := ParseCTYXML(f)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return ds
+}
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 12, 0, 0, 0, time.UTC)
+}
+
+func TestDataset_Resolve(t *testing.T) {
+	ds := loadTestDataset(t)
+
+	tests := []struct {
+		name   string
+		call   string
+		at     time.Time
+		adif   int
+		exact  bool
+		cqZone int
+	}{
+		{name: "prefix", call: "K1ABC", at: date(2026, 1, 1), adif: 291, cqZone: 5},
+		{name: "exception in range", call: "KC6BPD", at: date(1994, 6, 1), adif: 22, exact: true, cqZone: 27},
+		{name: "exception out of range", call: "KC6BPD", at: date(1996, 6, 1), adif: 291, cqZone: 5},
+		{name: "dated prefix before change", call: "Y21ABC", at: date(1985, 1, 1), adif: 229, cqZone: 14},
+		{name: "dated prefix after change", call: "Y21ABC", at: date(2000, 1, 1), adif: 230, cqZone: 14},
+		{name: "zone exception", call: "K7ABC", at: date(2026, 1, 1), adif: 291, cqZone: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ds.Resolve(tt.call, tt.at)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if m.Record.ADIF != tt.adif || m.Exact != tt.exact || m.CQZone != tt.cqZone {
+				t.Fatalf("unexpected match: %#v", m)
+			}
+			if m.Entity == nil || m.Entity.ADIF != tt.adif {
+				t.Fatalf("expected entity %d, got %#v", tt.adif, m.Entity)
+			}
+		})
+	}
+}
+
+func TestDataset_Resolve_InvalidOperation(t *testing.T) {
+	ds := loadTestDataset(t)
+
+	_, err := ds.Resolve("T88XX", date(2020, 6, 1))
+	if !stderrors.Is(err, ErrInvalidOperation) {
+		t.Fatalf("expected ErrInvalidOperation, got %v", err)
+	}
+
+	// Outside the listed period the call resolves normally.
+	m, err := ds.Resolve("T88XX", date(2022, 6, 1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.Record.ADIF != 22 {
+		t.Fatalf("expected Palau, got %#v", m)
+	}
+}
+
+func TestDataset_Resolve_NotFound(t *testing.T) {
+	ds := loadTestDataset(t)
+
+	_, err := ds.Resolve("QQ1ABC", date(2026, 1, 1))
+	if !stderrors.Is(err, smerrors.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestParseCTYXML_Invalid(t *testing.T) {
+	inputs := map[string]string{
+		"not xml":     "cty.dat is not xml",
+		"no entities": `<clublog date="2026-10-01T00:00:00+00:00"><entities></entities></clublog>`,
+		"bad date":    `<clublog><entities><entity><adif>1</adif><name>CANADA</name><start>yesterday</start></entity></entities></clublog>`,
+	}
+	for name, input := range inputs {
+		if _, err := ParseCTYXML(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package clublog
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Station-Manager/config"
+	"github.com/Station-Manager/errors"
+	"github.com/Station-Manager/logging"
+	"github.com/Station-Manager/lookup/internal/calls"
+	"github.com/Station-Manager/lookup/internal/dataset"
+	"github.com/Station-Manager/lookup/internal/upstream"
+	"github.com/Station-Manager/types"
+)
+
+const (
+	// ServiceName identifies the Club Log cty.xml lookup configuration and provider. The
+	// config's URL holds the path (or file:// URL) of the cty.xml file.
+	ServiceName = "clubloglookupservice"
+)
+
+// Service resolves callsigns to DXCC entities from Club Log's cty.xml, honouring its
+// callsign exceptions, invalid operations and zone exceptions.
+type Service struct {
+	ConfigService *config.Service  `di.inject:"configservice"`
+	LoggerService *logging.Service `di.inject:"loggingservice"`
+	Config        *types.LookupConfig
+
+	isInitialized atomic.Bool
+	initOnce      sync.Once
+
+	mu      sync.RWMutex
+	dataset *Dataset
+}
+
+// NewService returns a Club Log lookup service with the provided dependencies. The
+// config.Service is optional if you supply Config directly.
+func NewService(logger *logging.Service, cfgSvc *config.Service, cfg *types.LookupConfig) *Service {
+	return &Service{
+		LoggerService: logger,
+		ConfigService: cfgSvc,
+		Config:        cfg,
+	}
+}
+
+// Initialize initializes the Service instance and loads the configured cty.xml file.
+func (s *Service) Initialize() error {
+	const op errors.Op = "clublog.Service.Initialize"
+	if s.isInitialized.Load() {
+		return nil
+	}
+
+	var initErr error
+	s.initOnce.Do(func() {
+		if s.LoggerService == nil {
+			initErr = errors.New(op).Msg("logger service has not been set/injected")
+			return
+		}
+
+		if s.Config == nil {
+			if s.ConfigService == nil {
+				initErr = errors.New(op).Msg("application config has not been set/injected")
+				return
+			}
+
+			cfg, err := s.ConfigService.LookupServiceConfig(ServiceName)
+			if err != nil {
+				initErr = errors.New(op).Err(err).Msg("getting lookup service config")
+				return
+			}
+			s.Config = &cfg
+		}
+
+		if err := s.validateConfig(op); err != nil {
+			initErr = err
+			return
+		}
+
+		if s.Config.Enabled {
+			if err := s.Reload(); err != nil {
+				initErr = err
+				return
+			}
+		} else {
+			s.LoggerService.InfoWith().Msg("Club Log cty.xml lookup is disabled in the config")
+		}
+
+		s.isInitialized.Store(true)
+	})
+
+	return initErr
+}
+
+// Reload re-reads the configured cty.xml file, replacing the current dataset only if the
+// new file parses successfully.
+func (s *Service) Reload() error {
+	const op errors.Op = "clublog.Service.Reload"
+	if s.Config == nil {
+		return errors.New(op).Msg("service config is not set")
+	}
+
+	path := dataset.Path(s.Config.URL)
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.New(op).Err(err).Msgf("opening cty.xml file %q", path)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	ds, err := ParseCTYXML(f)
+	if err != nil {
+		return errors.New(op).Err(err).Msgf("parsing cty.xml file %q", path)
+	}
+
+	s.mu.Lock()
+	s.dataset = ds
+	s.mu.Unlock()
+
+	s.LoggerService.InfoWith().Str("path", path).Time("date", ds.Date).Msg("Loaded Club Log cty.xml dataset")
+	return nil
+}
+
+// SetDataset replaces the dataset used for lookups.
+func (s *Service) SetDataset(ds *Dataset) {
+	s.mu.Lock()
+	s.dataset = ds
+	s.mu.Unlock()
+}
+
+// Lookup performs a country lookup using a callsign and returns the corresponding country information or an error.
+func (s *Service) Lookup(callsign string) (types.Country, error) {
+	return s.LookupWithContext(context.Background(), callsign)
+}
+
+// LookupWithContext resolves the callsign as of the current time.
+func (s *Service) LookupWithContext(ctx context.Context, callsign string) (types.Country, error) {
+	return s.resolve(ctx, callsign, time.Now().UTC())
+}
+
+// LookupAt resolves the callsign as it was assigned at time at, so QSOs from old logs
+// are credited to the period-correct entity.
+func (s *Service) LookupAt(ctx context.Context, callsign string, at time.Time) (types.Country, error) {
+	return s.resolve(ctx, callsign, at.UTC())
+}
+
+// resolve resolves the callsign as of time at.
+func (s *Service) resolve(ctx context.Context, callsign string, at time.Time) (types.Country, error) {
+	const op errors.Op = "clublog.Service.resolve"
+	emptyRetVal := types.Country{}
+
+	if ctx != nil {
+		if err := ctx.Err(); err != nil {
+			return emptyRetVal, errors.New(op).Err(err).Msg("lookup cancelled")
+		}
+	}
+
+	if !s.isInitialized.Load() {
+		return emptyRetVal, errors.New(op).Msg("service is not initialized")
+	}
+	if s.Config == nil {
+		return emptyRetVal, errors.New(op).Msg("service config is not set")
+	}
+
+	if !s.Config.Enabled {
+		if s.LoggerService != nil {
+			s.LoggerService.InfoWith().Msg("Club Log cty.xml lookup is disabled in the config")
+		}
+		return types.Country{Name: "Unknown"}, errors.New(op).Err(upstream.ErrDisabled).Msg("Club Log cty.xml lookup is disabled in the config")
+	}
+
+	callsign = strings.ToUpper(strings.TrimSpace(callsign))
+	if callsign == "" {
+		return emptyRetVal, errors.New(op).Err(upstream.ErrInvalidCallsign).Msg("callsign cannot be empty")
+	}
+
+	s.mu.RLock()
+	ds := s.dataset
+	s.mu.RUnlock()
+	if ds == nil {
+		return emptyRetVal, errors.New(op).Msg("Club Log dataset is not loaded")
+	}
+
+	cs, err := calls.Parse(callsign)
+	if err != nil {
+		return emptyRetVal, errors.New(op).Err(err).Msg("invalid callsign")
+	}
+
+	m, err := resolveCall(ds, cs, at)
+	if err != nil {
+		return emptyRetVal, err
+	}
+
+	return m.Country(), nil
+}
+
+// resolveCall resolves a parsed callsign. Club Log lists many slashed calls verbatim as
+// exceptions or invalid operations, so the full form is used when it has such an entry;
+// otherwise the call the station is effectively operating under is resolved. Maritime
+// and aeronautical mobile stations resolve to no entity.
+func resolveCall(ds *Dataset, cs calls.Callsign, at time.Time) (*Match, error) {
+	const op errors.Op = "clublog.resolveCall"
+
+	if _, ok := firstValid(ds.exceptions[cs.Raw], at); ok {
+		return ds.Resolve(cs.Raw, at)
+	}
+	for _, v := range ds.invalid[cs.Raw] {
+		if v.Contains(at) {
+			return ds.Resolve(cs.Raw, at)
+		}
+	}
+	if cs.HasNoEntity() {
+		return nil, errors.New(op).Err(errors.ErrNotFound).Msgf("%s is %s and has no DXCC entity", cs.Raw, cs.Indicator)
+	}
+	return ds.Resolve(cs.LookupCall(), at)
+}
+
+// Country maps the match into the shared types.Country model. Club Log does not publish
+// ITU zones or UTC offsets, so those fields are left empty.
+func (m *Match) Country() types.Country {
+	country := types.Country{
+		Name:      m.Record.Name,
+		Prefix:    m.Record.Call,
+		Continent: m.Record.Continent,
+	}
+	if m.Entity != nil {
+		country.DXCCPrefix = m.Entity.Prefix
+		if country.Name == "" {
+			country.Name = m.Entity.Name
+		}
+	}
+	if m.CQZone != 0 {
+		country.CQZone = strconv.Itoa(m.CQZone)
+	}
+	return country
+}
+
+func (s *Service) validateConfig(op errors.Op) error {
+	if s.Config == nil {
+		return errors.New(op).Msg("service config is not set")
+	}
+
+	if !s.Config.Enabled {
+		return nil
+	}
+
+	if dataset.Path(s.Config.URL) == "" {
+		return errors.New(op).Msg("cty.xml file path (URL) cannot be empty")
+	}
+
+	return nil
+}
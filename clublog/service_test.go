@@ -0,0 +1,63 @@
+package clublog
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Station-Manager/logging"
+	"github.com/Station-Manager/types"
+)
+
+func TestService_Lookup(t *testing.T) {
+	s := NewService(&logging.Service{}, nil, &types.LookupConfig{Enabled: true, URL: "testdata/cty.xml"})
+	if err := s.Initialize(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	country, err := s.Lookup("k1abc")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := types.Country{
+		Name:       "UNITED STATES OF AMERICA",
+		Prefix:     "K",
+		Continent:  "NA",
+		CQZone:     "5",
+		DXCCPrefix: "K",
+	}
+	if country != want {
+		t.Fatalf("unexpected country: got %#v want %#v", country, want)
+	}
+}
+
+func TestService_Initialize_MissingFile(t *testing.T) {
+	s := NewService(&logging.Service{}, nil, &types.LookupConfig{Enabled: true, URL: "testdata/missing.xml"})
+
+	if err := s.Initialize(); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+}
+
+func TestService_LookupAt(t *testing.T) {
+	s := NewService(&logging.Service{}, nil, &types.LookupConfig{Enabled: true, URL: "testdata/cty.xml"})
+	if err := s.Initialize(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	country, err := s.LookupAt(context.Background(), "Y21ABC", time.Date(1989, time.May, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if country.Name != "GERMAN DEMOCRATIC REPUBLIC" || country.DXCCPrefix != "Y2" {
+		t.Fatalf("unexpected country: %#v", country)
+	}
+
+	country, err = s.LookupWithContext(context.Background(), "Y21ABC")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if country.Name != "FEDERAL REPUBLIC OF GERMANY" {
+		t.Fatalf("unexpected country: %#v", country)
+	}
+}
@@ -2,11 +2,20 @@ package lookup
 
 import (
 	"context"
+	"sync"
 
 	"github.com/Station-Manager/config"
 	"github.com/Station-Manager/errors"
 	"github.com/Station-Manager/logging"
+	"github.com/Station-Manager/lookup/breaker"
+	"github.com/Station-Manager/lookup/clublog"
 	"github.com/Station-Manager/lookup/hamnut"
+	"github.com/Station-Manager/lookup/hamqth"
+	"github.com/Station-Manager/lookup/offline"
+	"github.com/Station-Manager/lookup/qrz"
+	"github.com/Station-Manager/lookup/ratelimit"
+	"github.com/Station-Manager/lookup/retry"
+	"github.com/Station-Manager/lookup/transport"
 	"github.com/Station-Manager/types"
 )
 
@@ -17,11 +26,65 @@ type Provider interface {
 	LookupWithContext(ctx context.Context, callsign string) (types.Country, error)
 }
 
+// StationProvider defines the behavior a callbook (station-level) lookup provider must
+// implement. Unlike Provider, which resolves a callsign to its DXCC entity, a
+// StationProvider returns the details of the individual station (name, QTH, grid, etc.).
+type StationProvider interface {
+	Initialize() error
+	Lookup(callsign string) (types.ContactedStation, error)
+	LookupWithContext(ctx context.Context, callsign string) (types.ContactedStation, error)
+}
+
+// BreakerReporter is implemented by providers guarded by a circuit breaker, so that a
+// UI can show each provider's health.
+type BreakerReporter interface {
+	BreakerState() breaker.State
+}
+
+// Compile-time checks that the bundled providers satisfy their contracts.
+var (
+	_ Provider = (*hamnut.Service)(nil)
+	_ Provider = (*qrz.DXCCService)(nil)
+	_ Provider = (*offline.Service)(nil)
+	_ Provider = (*clublog.Service)(nil)
+	_ Provider = (*CachedProvider)(nil)
+	_ Provider = (*Chain)(nil)
+	_ Provider = (*ObservedProvider)(nil)
+
+	_ HistoricalProvider = (*clublog.Service)(nil)
+	_ HistoricalProvider = (*CachedProvider)(nil)
+	_ HistoricalProvider = (*Chain)(nil)
+	_ HistoricalProvider = (*ObservedProvider)(nil)
+	_ StationProvider    = (*qrz.Service)(nil)
+	_ StationProvider    = (*hamqth.Service)(nil)
+	_ StationProvider    = (*CachedStationProvider)(nil)
+	_ StationProvider    = (*Merger)(nil)
+	_ StationProvider    = (*ObservedStationProvider)(nil)
+
+	_ BreakerReporter = (*hamnut.Service)(nil)
+	_ BreakerReporter = (*qrz.Service)(nil)
+	_ BreakerReporter = (*qrz.DXCCService)(nil)
+	_ BreakerReporter = (*hamqth.Service)(nil)
+)
+
+// ProviderSettings holds the per-provider settings that types.LookupConfig has no room
+// for. It carries JSON tags so an application can keep it in its own config next to each
+// provider's LookupConfig.
+type ProviderSettings struct {
+	Transport transport.Config `json:"transport"`
+	RateLimit ratelimit.Config `json:"rate_limit"`
+	Retry     retry.Policy     `json:"retry"`
+	Breaker   breaker.Config   `json:"breaker"`
+}
+
 // ServiceFactory creates lookup providers by name. It can be extended to return
-// other providers (e.g., QRZ, HamQTH) as they are implemented.
+// other providers as they are implemented.
 type ServiceFactory struct {
 	logger *logging.Service
 	config *config.Service
+
+	mu       sync.RWMutex
+	settings map[string]ProviderSettings
 }
 
 // NewServiceFactory constructs a factory capable of returning lookup providers backed by
@@ -30,16 +93,92 @@ func NewServiceFactory(logger *logging.Service, cfg *config.Service) *ServiceFac
 	return &ServiceFactory{logger: logger, config: cfg}
 }
 
+// Configure sets the settings applied to providers the factory creates under name from
+// now on. qrz.DXCCServiceName shares the QRZ.com settings, registered under its
+// ServiceName. It returns the factory so calls can be chained.
+func (f *ServiceFactory) Configure(name string, settings ProviderSettings) *ServiceFactory {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.settings == nil {
+		f.settings = make(map[string]ProviderSettings)
+	}
+	f.settings[name] = settings
+	return f
+}
+
+// providerSettings returns the settings registered for name, or the zero value.
+func (f *ServiceFactory) providerSettings(name string) ProviderSettings {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.settings[name]
+}
+
 // NewProvider creates a lookup provider with the given service name.
 func (f *ServiceFactory) NewProvider(name string) (Provider, error) {
 	switch name {
 	case types.HamNutLookupServiceName:
-		return hamnut.NewService(f.logger, f.config, nil, nil), nil
+		settings := f.providerSettings(name)
+		svc := hamnut.NewService(f.logger, f.config, nil, nil)
+		svc.Transport = settings.Transport
+		svc.RateLimit = settings.RateLimit
+		svc.Retry = settings.Retry
+		svc.Breaker = settings.Breaker
+		return svc, nil
+	case qrz.DXCCServiceName:
+		return qrz.NewDXCCService(f.newQRZService()), nil
+	case offline.ServiceName:
+		return offline.NewService(f.logger, f.config, nil), nil
+	case clublog.ServiceName:
+		return clublog.NewService(f.logger, f.config, nil), nil
 	default:
 		return nil, errors.New("lookup.ServiceFactory.NewProvider").Msgf("unsupported lookup provider %q", name)
 	}
 }
 
+// NewStationProvider creates a station-level lookup provider with the given service name.
+func (f *ServiceFactory) NewStationProvider(name string) (StationProvider, error) {
+	switch name {
+	case types.QrzLookupServiceName:
+		return f.newQRZService(), nil
+	case hamqth.ServiceName:
+		settings := f.providerSettings(name)
+		svc := hamqth.NewService(f.logger, f.config, nil, nil)
+		svc.Transport = settings.Transport
+		svc.RateLimit = settings.RateLimit
+		svc.Retry = settings.Retry
+		svc.Breaker = settings.Breaker
+		return svc, nil
+	default:
+		return nil, errors.New("lookup.ServiceFactory.NewStationProvider").Msgf("unsupported station lookup provider %q", name)
+	}
+}
+
+// newQRZService returns a QRZ.com service carrying the settings registered under
+// qrz.ServiceName; the callsign and DXCC providers both use it.
+func (f *ServiceFactory) newQRZService() *qrz.Service {
+	settings := f.providerSettings(qrz.ServiceName)
+	svc := qrz.NewService(f.logger, f.config, nil, nil)
+	svc.Transport = settings.Transport
+	svc.RateLimit = settings.RateLimit
+	svc.Retry = settings.Retry
+	svc.Breaker = settings.Breaker
+	return svc
+}
+
+// NewProviderChain creates a failover Chain from the named providers, consulted in the
+// given order, e.g. Hamnut, then QRZ.com DXCC, then the offline cty.dat dataset.
+func (f *ServiceFactory) NewProviderChain(names ...string) (*Chain, error) {
+	links := make([]ChainLink, 0, len(names))
+	for _, name := range names {
+		p, err := f.NewProvider(name)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, ChainLink{Name: name, Provider: p})
+	}
+	return NewChain(links...), nil
+}
+
 // MustProvider returns a provider or panics.
 func (f *ServiceFactory) MustProvider(name string) Provider {
 	p, err := f.NewProvider(name)
@@ -48,3 +187,12 @@ func (f *ServiceFactory) MustProvider(name string) Provider {
 	}
 	return p
 }
+
+// MustStationProvider returns a station-level provider or panics.
+func (f *ServiceFactory) MustStationProvider(name string) StationProvider {
+	p, err := f.NewStationProvider(name)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
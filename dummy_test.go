@@ -0,0 +1,157 @@
+package lookup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Station-Manager/lookup/breaker"
+	"github.com/Station-Manager/lookup/hamnut"
+	"github.com/Station-Manager/lookup/hamqth"
+	"github.com/Station-Manager/lookup/qrz"
+	"github.com/Station-Manager/lookup/ratelimit"
+	"github.com/Station-Manager/lookup/retry"
+	"github.com/Station-Manager/lookup/transport"
+	"github.com/Station-Manager/types"
+)
+
+func TestServiceFactory_ConfigureAppliesSettings(t *testing.T) {
+	proxied := ProviderSettings{Transport: transport.Config{ProxyURL: "http://proxy.example:3128"}}
+	f := NewServiceFactory(nil, nil).
+		Configure(types.HamNutLookupServiceName, proxied).
+		Configure(qrz.ServiceName, proxied).
+		Configure(hamqth.ServiceName, proxied)
+
+	p, err := f.NewProvider(types.HamNutLookupServiceName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := p.(*hamnut.Service).Transport.ProxyURL; got != proxied.Transport.ProxyURL {
+		t.Fatalf("hamnut: expected proxy %q, got %q", proxied.Transport.ProxyURL, got)
+	}
+
+	p, err = f.NewProvider(qrz.DXCCServiceName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := p.(*qrz.DXCCService).Service().Transport.ProxyURL; got != proxied.Transport.ProxyURL {
+		t.Fatalf("qrz dxcc: expected proxy %q, got %q", proxied.Transport.ProxyURL, got)
+	}
+
+	for _, name := range []string{types.QrzLookupServiceName, hamqth.ServiceName} {
+		sp, err := f.NewStationProvider(name)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		var got string
+		switch svc := sp.(type) {
+		case *qrz.Service:
+			got = svc.Transport.ProxyURL
+		case *hamqth.Service:
+			got = svc.Transport.ProxyURL
+		}
+		if got != proxied.Transport.ProxyURL {
+			t.Fatalf("%s: expected proxy %q, got %q", name, proxied.Transport.ProxyURL, got)
+		}
+	}
+}
+
+func TestServiceFactory_UnconfiguredProviderKeepsDefaults(t *testing.T) {
+	f := NewServiceFactory(nil, nil).Configure(qrz.ServiceName, ProviderSettings{
+		Transport: transport.Config{ProxyURL: "http://proxy.example:3128"},
+	})
+
+	p, err := f.NewProvider(types.HamNutLookupServiceName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !p.(*hamnut.Service).Transport.IsZero() {
+		t.Fatalf("expected zero transport for hamnut, got %#v", p.(*hamnut.Service).Transport)
+	}
+}
+
+func TestServiceFactory_ConfigureAppliesRateLimit(t *testing.T) {
+	limit := ratelimit.Config{Rate: 1, Burst: 2}
+	f := NewServiceFactory(nil, nil).
+		Configure(types.HamNutLookupServiceName, ProviderSettings{RateLimit: limit}).
+		Configure(qrz.ServiceName, ProviderSettings{RateLimit: limit})
+
+	p, err := f.NewProvider(types.HamNutLookupServiceName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := p.(*hamnut.Service).RateLimit; got != limit {
+		t.Fatalf("hamnut: expected rate limit %#v, got %#v", limit, got)
+	}
+
+	sp, err := f.NewStationProvider(types.QrzLookupServiceName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := sp.(*qrz.Service).RateLimit; got != limit {
+		t.Fatalf("qrz: expected rate limit %#v, got %#v", limit, got)
+	}
+}
+
+func TestServiceFactory_ConfigureAppliesRetry(t *testing.T) {
+	policy := retry.Policy{MaxAttempts: 1}
+	f := NewServiceFactory(nil, nil).
+		Configure(types.HamNutLookupServiceName, ProviderSettings{Retry: policy}).
+		Configure(qrz.ServiceName, ProviderSettings{Retry: policy}).
+		Configure(hamqth.ServiceName, ProviderSettings{Retry: policy})
+
+	p, err := f.NewProvider(types.HamNutLookupServiceName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := p.(*hamnut.Service).Retry.MaxAttempts; got != 1 {
+		t.Fatalf("hamnut: expected MaxAttempts 1, got %d", got)
+	}
+
+	p, err = f.NewProvider(qrz.DXCCServiceName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := p.(*qrz.DXCCService).Service().Retry.MaxAttempts; got != 1 {
+		t.Fatalf("qrz dxcc: expected MaxAttempts 1, got %d", got)
+	}
+
+	sp, err := f.NewStationProvider(hamqth.ServiceName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := sp.(*hamqth.Service).Retry.MaxAttempts; got != 1 {
+		t.Fatalf("hamqth: expected MaxAttempts 1, got %d", got)
+	}
+}
+
+func TestServiceFactory_ConfigureAppliesBreaker(t *testing.T) {
+	cfg := breaker.Config{FailureThreshold: 3, OpenFor: time.Minute}
+	f := NewServiceFactory(nil, nil).
+		Configure(types.HamNutLookupServiceName, ProviderSettings{Breaker: cfg}).
+		Configure(qrz.ServiceName, ProviderSettings{Breaker: cfg}).
+		Configure(hamqth.ServiceName, ProviderSettings{Breaker: cfg})
+
+	p, err := f.NewProvider(types.HamNutLookupServiceName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := p.(*hamnut.Service).Breaker; got.FailureThreshold != 3 || got.OpenFor != time.Minute {
+		t.Fatalf("hamnut: unexpected breaker config %#v", got)
+	}
+
+	sp, err := f.NewStationProvider(types.QrzLookupServiceName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := sp.(*qrz.Service).Breaker; got.FailureThreshold != 3 || got.OpenFor != time.Minute {
+		t.Fatalf("qrz: unexpected breaker config %#v", got)
+	}
+
+	sp, err = f.NewStationProvider(hamqth.ServiceName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := sp.(*hamqth.Service).Breaker; got.FailureThreshold != 3 || got.OpenFor != time.Minute {
+		t.Fatalf("hamqth: unexpected breaker config %#v", got)
+	}
+}
@@ -0,0 +1,68 @@
+package lookup
+
+import (
+	stderr "errors"
+
+	"github.com/Station-Manager/errors"
+	"github.com/Station-Manager/lookup/breaker"
+	"github.com/Station-Manager/lookup/internal/upstream"
+)
+
+// Errors shared by every provider, for use with errors.Is. Providers wrap them with
+// context, so always test with errors.Is rather than ==.
+var (
+	// ErrNotFound reports a callsign or prefix the provider has no record of. It is the
+	// Station-Manager errors package's ErrNotFound.
+	ErrNotFound = errors.ErrNotFound
+	// ErrDisabled is returned by a provider switched off through LookupConfig.Enabled,
+	// together with the provider's placeholder answer (e.g. Country{Name: "Unknown"}).
+	ErrDisabled = upstream.ErrDisabled
+	// ErrUnauthorized reports credentials rejected by the upstream (a refused login, or
+	// HTTP 401/403).
+	ErrUnauthorized = upstream.ErrUnauthorized
+	// ErrUnavailable reports an upstream that could not be reached, timed out, answered
+	// with a 5xx status or is behind an open circuit breaker.
+	ErrUnavailable = upstream.ErrUnavailable
+	// ErrInvalidCallsign reports a callsign rejected before any lookup was made.
+	ErrInvalidCallsign = upstream.ErrInvalidCallsign
+	// ErrMalformedResponse reports an upstream answer that could not be decoded.
+	ErrMalformedResponse = upstream.ErrMalformedResponse
+)
+
+// StatusError reports an unexpected HTTP status returned by a provider's upstream
+// service. Use errors.As to retrieve it from a provider error.
+type StatusError = upstream.StatusError
+
+// RateLimitError reports a request held back by a provider's client-side limiter or
+// refused upstream with 429/503. RetryAfter says how long the provider is paused for.
+type RateLimitError = upstream.RateLimitError
+
+// ErrRateLimited matches any RateLimitError via errors.Is.
+var ErrRateLimited = upstream.ErrRateLimited
+
+// ErrCircuitOpen matches the error a provider returns, without contacting its upstream,
+// while its circuit breaker is open.
+var ErrCircuitOpen = breaker.ErrOpen
+
+// IsNetworkError reports whether err means an upstream could not be reached or did not
+// answer in time: a network error, a timeout or a dropped connection.
+func IsNetworkError(err error) bool {
+	return upstream.IsConnectionFailure(err)
+}
+
+// IsTransient reports whether err describes a condition another attempt or another
+// provider may not hit: a network failure, a timeout, a 5xx response, a rate limit or an
+// open circuit breaker. Not-found results are definitive and never transient.
+func IsTransient(err error) bool {
+	if err == nil || stderr.Is(err, ErrNotFound) {
+		return false
+	}
+	if stderr.Is(err, ErrRateLimited) || stderr.Is(err, ErrUnavailable) {
+		return true
+	}
+	var se *StatusError
+	if stderr.As(err, &se) {
+		return se.Temporary()
+	}
+	return IsNetworkError(err)
+}
@@ -0,0 +1,89 @@
+package lookup
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Station-Manager/logging"
+	"github.com/Station-Manager/lookup/breaker"
+	"github.com/Station-Manager/lookup/hamnut"
+	"github.com/Station-Manager/lookup/retry"
+	"github.com/Station-Manager/types"
+)
+
+func TestProviderErrors_Taxonomy(t *testing.T) {
+	tests := []struct {
+		name     string
+		callsign string
+		handler  http.HandlerFunc
+		want     error
+	}{
+		{"not found", "K1ABC", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNotFound) }, ErrNotFound},
+		{"unauthorized", "K1ABC", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusForbidden) }, ErrUnauthorized},
+		{"unavailable", "K1ABC", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusBadGateway) }, ErrUnavailable},
+		{"rate limited", "K1ABC", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusTooManyRequests) }, ErrRateLimited},
+		{"malformed", "K1ABC", func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("<html>")) }, ErrMalformedResponse},
+		{"invalid callsign", "K/1/A/B", nil, ErrInvalidCallsign},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(tt.handler)
+			defer ts.Close()
+
+			cfg := types.LookupConfig{Enabled: true, URL: ts.URL, UserAgent: "test", HttpTimeoutSec: 5}
+			svc := hamnut.NewService(&logging.Service{}, nil, &cfg, ts.Client())
+			svc.Retry = retry.Policy{MaxAttempts: 1}
+			svc.Breaker = breaker.Config{FailureThreshold: -1}
+			if err := svc.Initialize(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			_, err := svc.LookupWithContext(context.Background(), tt.callsign)
+			if !stderrors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestProviderErrors_Unreachable(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	url := ts.URL
+	ts.Close()
+
+	cfg := types.LookupConfig{Enabled: true, URL: url, UserAgent: "test", HttpTimeoutSec: 5}
+	svc := hamnut.NewService(&logging.Service{}, nil, &cfg, nil)
+	svc.Retry = retry.Policy{MaxAttempts: 1}
+	if err := svc.Initialize(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err := svc.Lookup("K1ABC")
+	if !stderrors.Is(err, ErrUnavailable) || !IsNetworkError(err) || !IsTransient(err) {
+		t.Fatalf("expected a transient unavailable error, got %v", err)
+	}
+}
+
+func TestProviderErrors_Disabled(t *testing.T) {
+	svc := hamnut.NewService(&logging.Service{}, nil, &types.LookupConfig{Enabled: false}, nil)
+	if err := svc.Initialize(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	country, err := svc.Lookup("K1ABC")
+	if !stderrors.Is(err, ErrDisabled) || IsTransient(err) {
+		t.Fatalf("expected ErrDisabled, got %v", err)
+	}
+	if country.Name != "Unknown" {
+		t.Fatalf("expected the Unknown placeholder, got %q", country.Name)
+	}
+
+	result, err := NewChain(ChainLink{Name: "hamnut", Provider: svc}).LookupChained(context.Background(), "K1ABC")
+	if !stderrors.Is(err, ErrDisabled) || len(result.Skipped) != 1 {
+		t.Fatalf("expected the chain to report every provider disabled, got %#v %v", result, err)
+	}
+}
@@ -2,6 +2,7 @@ package hamnut
 
 import (
 	"github.com/Station-Manager/errors"
+	"github.com/Station-Manager/lookup/internal/upstream"
 	"github.com/Station-Manager/types"
 	"github.com/goccy/go-json"
 	"strconv"
@@ -16,7 +17,7 @@ func (s *Service) unmarshalResponse(body []byte) (types.Country, error) {
 
 	var resp PrefixLookupResponse
 	if err := json.Unmarshal(body, &resp); err != nil {
-		return country, errors.New(op).Err(err).Msg("decoding Hamnut response")
+		return country, errors.New(op).Err(upstream.Tag(upstream.ErrMalformedResponse, err)).Msg("decoding Hamnut response")
 	}
 
 	// If the upstream explicitly reports not found but still returns 2xx, treat it
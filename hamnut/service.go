@@ -2,6 +2,7 @@ package hamnut
 
 import (
 	"context"
+	stderr "errors"
 	"io"
 	"net/http"
 	"net/url"
@@ -13,20 +14,58 @@ import (
 	"github.com/Station-Manager/config"
 	"github.com/Station-Manager/errors"
 	"github.com/Station-Manager/logging"
+	"github.com/Station-Manager/lookup/breaker"
+	"github.com/Station-Manager/lookup/internal/calls"
+	"github.com/Station-Manager/lookup/internal/coalesce"
+	"github.com/Station-Manager/lookup/internal/guard"
+	"github.com/Station-Manager/lookup/internal/upstream"
+	"github.com/Station-Manager/lookup/metrics"
+	"github.com/Station-Manager/lookup/observer"
+	"github.com/Station-Manager/lookup/ratelimit"
+	"github.com/Station-Manager/lookup/retry"
+	"github.com/Station-Manager/lookup/transport"
 	"github.com/Station-Manager/types"
-	"github.com/Station-Manager/utils"
 )
 
 const (
 	ServiceName = types.HamNutLookupServiceName
 )
 
+// DefaultRateLimit is applied when Service.RateLimit is left at its zero value. Hamnut
+// is queried per prefix, and lookups of the same prefix share one request, so it gets a
+// higher default than the per-callsign callbooks.
+var DefaultRateLimit = ratelimit.Config{Rate: 5, Burst: 10}
+
 type Service struct {
 	ConfigService *config.Service  `di.inject:"configservice"`
 	LoggerService *logging.Service `di.inject:"loggingservice"`
 	Config        *types.LookupConfig
 	client        *http.Client
 
+	// Transport configures the HTTP client; see package transport. It is ignored when a
+	// client is passed to NewService.
+	Transport transport.Config
+
+	// RateLimit throttles requests; the zero value selects DefaultRateLimit.
+	RateLimit ratelimit.Config
+	limiter   *ratelimit.Limiter
+
+	// Retry governs retries of transient failures; see package retry.
+	Retry retry.Policy
+
+	// Breaker configures the circuit breaker; see package breaker.
+	Breaker breaker.Config
+	circuit *breaker.Breaker
+
+	// Metrics, if set, records every lookup under the provider label ServiceName.
+	Metrics metrics.Recorder
+
+	// Observer, if set, is told about every lookup and every request sent to Hamnut.
+	Observer observer.Observer
+
+	// inflight coalesces concurrent lookups of the same prefix into one request.
+	inflight coalesce.Group[string, types.Country]
+
 	isInitialized atomic.Bool
 	initOnce      sync.Once
 }
@@ -76,9 +115,20 @@ func (s *Service) Initialize() error {
 			return
 		}
 
+		if s.RateLimit == (ratelimit.Config{}) {
+			s.RateLimit = DefaultRateLimit
+		}
+		s.limiter = ratelimit.New(s.RateLimit)
+		s.circuit = breaker.New(s.Breaker)
+
 		if s.client == nil {
 			if s.Config.Enabled {
-				s.client = utils.NewHTTPClient(s.Config.HttpTimeoutSec * time.Second)
+				client, err := s.Transport.NewClient(s.Config.HttpTimeoutSec * time.Second)
+				if err != nil {
+					initErr = errors.New(op).Err(err).Msg("invalid HTTP transport settings")
+					return
+				}
+				s.client = client
 			} else {
 				s.LoggerService.InfoWith().Msg("Hamnut callsign/prefix lookup is disabled in the config")
 			}
@@ -98,6 +148,15 @@ func (s *Service) Lookup(callsign string) (types.Country, error) {
 // LookupWithContext performs a country lookup using the supplied context so callers
 // can enforce cancellation and deadlines.
 func (s *Service) LookupWithContext(ctx context.Context, callsign string) (types.Country, error) {
+	ctx, observed := observer.Start(ctx, s.Observer, ServiceName, callsign)
+	done := metrics.Start(s.Metrics, ServiceName)
+	country, err := s.lookup(ctx, callsign)
+	done(err)
+	observed(err)
+	return country, err
+}
+
+func (s *Service) lookup(ctx context.Context, callsign string) (types.Country, error) {
 	const op errors.Op = "hamnut.Service.LookupWithContext"
 	if ctx == nil {
 		ctx = context.Background()
@@ -119,7 +178,7 @@ func (s *Service) LookupWithContext(ctx context.Context, callsign string) (types
 		if s.LoggerService != nil {
 			s.LoggerService.InfoWith().Msg("Hamnut callsign/prefix lookup is disabled in the config")
 		}
-		return types.Country{Name: "Unknown"}, nil
+		return types.Country{Name: "Unknown"}, errors.New(op).Err(upstream.ErrDisabled).Msg("Hamnut callsign/prefix lookup is disabled in the config")
 	}
 
 	if s.client == nil {
@@ -127,17 +186,64 @@ func (s *Service) LookupWithContext(ctx context.Context, callsign string) (types
 	}
 
 	if callsign == "" {
-		return emptyRetVal, errors.New(op).Msg("callsign cannot be empty")
+		return emptyRetVal, errors.New(op).Err(upstream.ErrInvalidCallsign).Msg("callsign cannot be empty")
+	}
+
+	// Normalise and validate before anything reaches the network, so that mistyped or
+	// garbage input does not cost a request.
+	cs, err := calls.Validate(callsign)
+	if err != nil {
+		return emptyRetVal, errors.New(op).Err(err).Msg("invalid callsign")
+	}
+	if cs.HasNoEntity() {
+		return emptyRetVal, errors.New(op).Err(errors.ErrNotFound).Msgf("%s is %s and has no DXCC entity", cs.Raw, cs.Indicator)
+	}
+
+	if err = s.limiter.Admit(ctx); err != nil {
+		return emptyRetVal, errors.New(op).Err(err).Msg("Hamnut is held back by the rate limiter")
+	}
+
+	// Concurrent lookups of the same prefix (logging UI, cluster panel, map) share one
+	// request; each caller still returns as soon as its own context is done.
+	prefix := cs.LookupCall()
+	country, _, err := s.inflight.Do(ctx, prefix, func(ctx context.Context) (types.Country, error) {
+		return s.fetch(ctx, prefix)
+	})
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil && stderr.Is(err, ctxErr) {
+			return emptyRetVal, errors.New(op).Err(err).Msg("Failed to perform HTTP GET request")
+		}
+		return emptyRetVal, err
 	}
 
+	return country, nil
+}
+
+// fetch queries Hamnut for a single prefix, retrying transient failures as configured
+// by Retry. While the circuit breaker is open it fails straight away.
+func (s *Service) fetch(ctx context.Context, prefix string) (types.Country, error) {
+	return guard.Do(ctx, s.upstream(), func(ctx context.Context, attempt int) (types.Country, error) {
+		return s.fetchOnce(ctx, prefix, attempt)
+	})
+}
+
+// fetchOnce performs a single Hamnut request; attempt numbers it for the Observer.
+func (s *Service) fetchOnce(ctx context.Context, prefix string, attempt int) (types.Country, error) {
+	const op errors.Op = "hamnut.Service.fetchOnce"
+	emptyRetVal := types.Country{}
+
 	u, err := url.Parse(s.Config.URL)
 	if err != nil {
 		return emptyRetVal, errors.New(op).Err(err).Msg("invalid Hamnut base URL")
 	}
 	q := u.Query()
-	q.Set("prefix", callsign)
+	q.Set("prefix", prefix)
 	u.RawQuery = q.Encode()
 
+	if err = s.limiter.Wait(ctx); err != nil {
+		return emptyRetVal, errors.New(op).Err(err).Msg("Request held back by the rate limiter")
+	}
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
 		return emptyRetVal, errors.New(op).Err(err).Msg("Failed to create HTTP GET request")
@@ -146,26 +252,33 @@ func (s *Service) LookupWithContext(ctx context.Context, callsign string) (types
 	req.Header.Set("User-Agent", s.Config.UserAgent)
 	req.Header.Set("Accept", "application/json")
 
+	observed := observer.Request(s.Observer, ServiceName, prefix, attempt, req)
 	resp, err := s.client.Do(req)
+	observed(resp, err)
 	if err != nil {
-		return emptyRetVal, errors.New(op).Err(err).Msg("Failed to perform HTTP GET request")
+		return emptyRetVal, errors.New(op).Err(upstream.Unreachable(ctx, err)).Msg("Failed to perform HTTP GET request")
 	}
 	defer func(Body io.ReadCloser) {
 		_ = Body.Close()
 	}(resp.Body)
 
+	if rle := upstream.RateLimitFromResponse(resp); rle != nil {
+		s.limiter.Pause(rle.RetryAfter)
+		return emptyRetVal, errors.New(op).Err(rle).Msgf("Hamnut rate limit exceeded (HTTP %d)", resp.StatusCode)
+	}
+
 	if resp.StatusCode == http.StatusNotFound {
 		return emptyRetVal, errors.New(op).Err(errors.ErrNotFound).Msg("Prefix not found by Hamnut")
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		b, _ := io.ReadAll(resp.Body)
-		return emptyRetVal, errors.New(op).Errorf("Service returned unexpected status %d: %s", resp.StatusCode, string(b))
+		return emptyRetVal, errors.New(op).Err(&upstream.StatusError{StatusCode: resp.StatusCode, Body: string(b)}).Msgf("Service returned unexpected status %d: %s", resp.StatusCode, string(b))
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return emptyRetVal, errors.New(op).Errorf("Failed to read response body: %w", err)
+		return emptyRetVal, errors.New(op).Err(upstream.Unreachable(ctx, err)).Msg("Failed to read response body")
 	}
 
 	country, err := s.unmarshalResponse(body)
@@ -176,6 +289,20 @@ func (s *Service) LookupWithContext(ctx context.Context, callsign string) (types
 	return country, nil
 }
 
+// BreakerState returns the state of the circuit breaker in front of Hamnut, or
+// breaker.Disabled if the provider is disabled in its config.
+func (s *Service) BreakerState() breaker.State {
+	if s.Config != nil && !s.Config.Enabled {
+		return breaker.Disabled
+	}
+	return s.circuit.State()
+}
+
+// upstream describes Hamnut to the guard that retries its requests.
+func (s *Service) upstream() guard.Upstream {
+	return guard.Upstream{Name: "Hamnut", Breaker: s.circuit, Retry: s.Retry, Logger: s.LoggerService}
+}
+
 func (s *Service) validateConfig(op errors.Op) error {
 	if s.Config == nil {
 		return errors.New(op).Msg("service config is not set")
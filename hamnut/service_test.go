@@ -3,16 +3,25 @@ package hamnut
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/Station-Manager/config"
 	smerrors "github.com/Station-Manager/errors"
 	"github.com/Station-Manager/logging"
+	"github.com/Station-Manager/lookup/breaker"
+	"github.com/Station-Manager/lookup/internal/upstream"
+	"github.com/Station-Manager/lookup/metrics"
+	"github.com/Station-Manager/lookup/observer"
+	"github.com/Station-Manager/lookup/ratelimit"
+	"github.com/Station-Manager/lookup/retry"
 	"github.com/Station-Manager/types"
 )
 
@@ -78,8 +87,8 @@ func TestService_Lookup_DisabledConfig(t *testing.T) {
 	s.isInitialized.Store(true)
 
 	country, err := s.Lookup("  K1ABC  ")
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+	if !errors.Is(err, upstream.ErrDisabled) {
+		t.Fatalf("expected ErrDisabled, got %v", err)
 	}
 	if country.Name != "Unknown" {
 		t.Fatalf("expected Name to be callsign, got %q", country.Name)
@@ -262,3 +271,338 @@ func TestService_Lookup_NoHTTPClient(t *testing.T) {
 		t.Fatalf("expected 'http client is not configured' error, got %v", err)
 	}
 }
+
+func TestService_Lookup_QueriesEffectivePrefix(t *testing.T) {
+	var got string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Query().Get("prefix")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"ok","found":true,"_t":"2025-11-30T13:31:07.321Z","countryName":"Anguilla","prefix":"VP2E","cqZone":8,"ituZone":11}`))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	cfg := types.LookupConfig{Enabled: true, URL: ts.URL, UserAgent: "test"}
+	s := &Service{Config: &cfg, client: ts.Client(), LoggerService: &logging.Service{}}
+	s.isInitialized.Store(true)
+
+	if _, err := s.Lookup("vp2e/k1abc/p"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "VP2E" {
+		t.Fatalf("expected prefix query VP2E, got %q", got)
+	}
+
+	if _, err := s.Lookup("K1ABC/MM"); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+}
+
+func TestService_LookupWithContext_CoalescesConcurrentLookups(t *testing.T) {
+	var requests atomic.Int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"ok","found":true,"_t":"2025-11-30T13:31:07.321Z","countryName":"TestLand","prefix":"K1","cqZone":14,"ituZone":28}`))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	cfg := types.LookupConfig{Enabled: true, URL: ts.URL, UserAgent: "test"}
+	s := &Service{Config: &cfg, client: ts.Client(), LoggerService: &logging.Service{}}
+	s.isInitialized.Store(true)
+
+	var wg sync.WaitGroup
+	for _, call := range []string{"K1ABC", "k1abc", " K1ABC ", "K1ABC/P", "K1ABC"} {
+		wg.Add(1)
+		go func(call string) {
+			defer wg.Done()
+			if country, err := s.Lookup(call); err != nil || country.Name != "TestLand" {
+				t.Errorf("unexpected result for %q: %#v %v", call, country, err)
+			}
+		}(call)
+	}
+	wg.Wait()
+
+	if got := requests.Load(); got != 1 {
+		t.Fatalf("expected concurrent lookups to share one request, got %d", got)
+	}
+}
+
+func TestService_Lookup_RateLimitedPausesProvider(t *testing.T) {
+	var requests atomic.Int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	cfg := types.LookupConfig{Enabled: true, URL: ts.URL, UserAgent: "test", HttpTimeoutSec: 5}
+	s := NewService(&logging.Service{}, nil, &cfg, ts.Client())
+	if err := s.Initialize(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err := s.Lookup("K1ABC")
+	if !errors.Is(err, upstream.ErrRateLimited) {
+		t.Fatalf("expected rate-limited error, got %v", err)
+	}
+
+	// The provider is now paused; a caller with a short deadline is turned away
+	// without another request being made.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = s.LookupWithContext(ctx, "DL1XX")
+	var rle *upstream.RateLimitError
+	if !errors.As(err, &rle) || rle.StatusCode != 0 || rle.RetryAfter <= 0 {
+		t.Fatalf("expected client-side rate-limited error, got %v", err)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Fatalf("expected a single upstream request, got %d", got)
+	}
+}
+
+func TestService_LookupWithContext_ThrottledCallerGetsRateLimitError(t *testing.T) {
+	var requests atomic.Int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"ok","found":true,"countryName":"TestLand","prefix":"K1"}`))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	cfg := types.LookupConfig{Enabled: true, URL: ts.URL, UserAgent: "test", HttpTimeoutSec: 5}
+	s := NewService(&logging.Service{}, nil, &cfg, ts.Client())
+	s.RateLimit = ratelimit.Config{Rate: 0.1, Burst: 1}
+	if err := s.Initialize(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := s.Lookup("K1ABC"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The bucket is empty; a caller whose deadline ends before the next token gets the
+	// typed error rather than a bare deadline error.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := s.LookupWithContext(ctx, "DL1XX")
+	var rle *upstream.RateLimitError
+	if !errors.As(err, &rle) || rle.RetryAfter <= 0 {
+		t.Fatalf("expected client-side rate-limited error, got %v", err)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Fatalf("expected a single upstream request, got %d", got)
+	}
+}
+
+func TestService_Lookup_RetriesTransientFailure(t *testing.T) {
+	var requests atomic.Int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"ok","found":true,"countryName":"TestLand","prefix":"K1"}`))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	cfg := types.LookupConfig{Enabled: true, URL: ts.URL, UserAgent: "test"}
+	s := &Service{Config: &cfg, client: ts.Client(), LoggerService: &logging.Service{}}
+	s.Retry = retry.Policy{MaxAttempts: 3, BaseDelay: time.Millisecond, RetryableStatus: []int{http.StatusBadGateway}}
+	s.isInitialized.Store(true)
+
+	country, err := s.Lookup("K1ABC")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if country.Name != "TestLand" {
+		t.Fatalf("unexpected Name: %q", country.Name)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Fatalf("expected 2 upstream requests, got %d", got)
+	}
+
+	// Not-found answers are final.
+	ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	})
+	if _, err = s.Lookup("DL1XX"); !errors.Is(err, smerrors.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if got := requests.Load(); got != 3 {
+		t.Fatalf("expected not-found to be requested once, got %d requests", got)
+	}
+}
+
+func TestService_Lookup_CircuitBreakerFailsFast(t *testing.T) {
+	var requests atomic.Int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	cfg := types.LookupConfig{Enabled: true, URL: ts.URL, UserAgent: "test", HttpTimeoutSec: 5}
+	s := NewService(&logging.Service{}, nil, &cfg, ts.Client())
+	s.Retry = retry.Policy{MaxAttempts: 1}
+	s.Breaker = breaker.Config{FailureThreshold: 2, OpenFor: time.Minute}
+	if err := s.Initialize(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, call := range []string{"K1ABC", "DL1XX"} {
+		if _, err := s.Lookup(call); err == nil {
+			t.Fatalf("expected error, got nil")
+		}
+	}
+	if s.BreakerState() != breaker.Open {
+		t.Fatalf("expected the breaker to be open, got %v", s.BreakerState())
+	}
+
+	_, err := s.Lookup("G4ABC")
+	if !errors.Is(err, breaker.ErrOpen) {
+		t.Fatalf("expected ErrOpen, got %v", err)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Fatalf("expected no request while open, got %d requests", got)
+	}
+
+	cfg.Enabled = false
+	if s.BreakerState() != breaker.Disabled {
+		t.Fatalf("expected disabled, got %v", s.BreakerState())
+	}
+}
+
+func TestService_Lookup_NormalisesAndValidatesCallsign(t *testing.T) {
+	var prefixes []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefixes = append(prefixes, r.URL.Query().Get("prefix"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"ok","found":true,"countryName":"England","prefix":"M"}`))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	cfg := types.LookupConfig{Enabled: true, URL: ts.URL, UserAgent: "test"}
+	s := &Service{Config: &cfg, client: ts.Client(), LoggerService: &logging.Service{}}
+	s.isInitialized.Store(true)
+
+	for _, garbage := range []string{"TEST", "12345", "K1 ABC"} {
+		if _, err := s.Lookup(garbage); !errors.Is(err, upstream.ErrInvalidCallsign) {
+			t.Fatalf("%q: expected ErrInvalidCallsign, got %v", garbage, err)
+		}
+	}
+	if len(prefixes) != 0 {
+		t.Fatalf("expected no upstream request for invalid callsigns, got %v", prefixes)
+	}
+
+	if _, err := s.Lookup("mØabc"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(prefixes) != 1 || prefixes[0] != "M0ABC" {
+		t.Fatalf("expected the normalised callsign to be queried, got %v", prefixes)
+	}
+}
+
+func TestService_Lookup_RecordsMetrics(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"ok","found":true,"countryName":"TestLand","prefix":"K1"}`))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	reg := metrics.NewRegistry()
+	cfg := types.LookupConfig{Enabled: true, URL: ts.URL, UserAgent: "test"}
+	s := &Service{Config: &cfg, client: ts.Client(), LoggerService: &logging.Service{}, Metrics: reg}
+	s.isInitialized.Store(true)
+
+	if _, err := s.Lookup("K1ABC"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := s.Lookup("K1 ABC"); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+
+	var b strings.Builder
+	_ = reg.WritePrometheus(&b)
+	for _, want := range []string{
+		`lookup_requests_total{provider="` + ServiceName + `"} 2`,
+		`lookup_errors_total{provider="` + ServiceName + `",class="invalid_callsign"} 1`,
+		`lookup_in_flight{provider="` + ServiceName + `"} 0`,
+		`lookup_duration_seconds_count{provider="` + ServiceName + `"} 2`,
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Fatalf("metrics are missing %q:\n%s", want, b.String())
+		}
+	}
+}
+
+func TestService_Lookup_ReportsToObserver(t *testing.T) {
+	var requests atomic.Int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"ok","found":true,"countryName":"TestLand","prefix":"K1"}`))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	var mu sync.Mutex
+	var events []string
+	record := func(format string, args ...any) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, fmt.Sprintf(format, args...))
+	}
+	cfg := types.LookupConfig{Enabled: true, URL: ts.URL, UserAgent: "test"}
+	s := &Service{Config: &cfg, client: ts.Client(), LoggerService: &logging.Service{}}
+	s.Retry = retry.Policy{MaxAttempts: 3, BaseDelay: time.Millisecond, RetryableStatus: []int{http.StatusBadGateway}}
+	s.Observer = observer.Funcs{
+		Start: func(ctx context.Context, e observer.Event) context.Context {
+			record("start %s %s", e.Provider, e.Callsign)
+			return ctx
+		},
+		HTTPRequest: func(ctx context.Context, e observer.Event, req *http.Request) {
+			record("request %s %d", e.Callsign, e.Attempt)
+		},
+		HTTPResponse: func(ctx context.Context, e observer.Event, resp *http.Response, err error) {
+			record("response %d %d", e.Attempt, resp.StatusCode)
+		},
+		Result: func(ctx context.Context, e observer.Event) { record("result %s", e.Callsign) },
+	}
+	s.isInitialized.Store(true)
+
+	if _, err := s.Lookup("k1abc"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{
+		"start " + ServiceName + " k1abc",
+		"request K1ABC 1",
+		"response 1 502",
+		"request K1ABC 2",
+		"response 2 200",
+		"result k1abc",
+	}
+	if len(events) != len(want) {
+		t.Fatalf("unexpected events: %q", events)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Fatalf("unexpected events: %q", events)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package hamqth
+
+import (
+	"encoding/xml"
+)
+
+// Search models the search element returned by a HamQTH callsign query.
+type Search struct {
+	Callsign   string `xml:"callsign"`
+	Nick       string `xml:"nick"`
+	QTH        string `xml:"qth"`
+	Country    string `xml:"country"`
+	Adif       string `xml:"adif"`
+	Itu        string `xml:"itu"`
+	Cq         string `xml:"cq"`
+	Grid       string `xml:"grid"`
+	AdrName    string `xml:"adr_name"`
+	AdrStreet1 string `xml:"adr_street1"`
+	AdrStreet2 string `xml:"adr_street2"`
+	AdrStreet3 string `xml:"adr_street3"`
+	AdrCity    string `xml:"adr_city"`
+	AdrZip     string `xml:"adr_zip"`
+	AdrCountry string `xml:"adr_country"`
+	AdrAdif    string `xml:"adr_adif"`
+	District   string `xml:"district"`
+	UsState    string `xml:"us_state"`
+	UsCounty   string `xml:"us_county"`
+	Oblast     string `xml:"oblast"`
+	Dok        string `xml:"dok"`
+	Iota       string `xml:"iota"`
+	QslVia     string `xml:"qsl_via"`
+	Lotw       string `xml:"lotw"`
+	Eqsl       string `xml:"eqsl"`
+	Qsl        string `xml:"qsl"`
+	QslDirect  string `xml:"qsldirect"`
+	Email      string `xml:"email"`
+	Jabber     string `xml:"jabber"`
+	Icq        string `xml:"icq"`
+	Msn        string `xml:"msn"`
+	Skype      string `xml:"skype"`
+	BirthYear  string `xml:"birth_year"`
+	LicYear    string `xml:"lic_year"`
+	Picture    string `xml:"picture"`
+	Latitude   string `xml:"latitude"`
+	Longitude  string `xml:"longitude"`
+	Continent  string `xml:"continent"`
+	UtcOffset  string `xml:"utc_offset"`
+	Facebook   string `xml:"facebook"`
+	Twitter    string `xml:"twitter"`
+	GooglePlus string `xml:"gplus"`
+	Youtube    string `xml:"youtube"`
+	Linkedin   string `xml:"linkedin"`
+	Flicker    string `xml:"flicker"`
+	Vimeo      string `xml:"vimeo"`
+	Web        string `xml:"web"`
+}
+
+type Session struct {
+	SessionID string `xml:"session_id"`
+	Error     string `xml:"error"`
+}
+
+type Database struct {
+	XMLName xml.Name `xml:"HamQTH"`
+	Version string   `xml:"version,attr"`
+	Session Session  `xml:"session"`
+	Search  Search   `xml:"search"`
+}
@@ -0,0 +1,253 @@
+package hamqth
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Station-Manager/errors"
+	"github.com/Station-Manager/lookup/breaker"
+	"github.com/Station-Manager/lookup/internal/guard"
+	"github.com/Station-Manager/lookup/internal/session"
+	"github.com/Station-Manager/lookup/internal/upstream"
+	"github.com/Station-Manager/lookup/observer"
+	"github.com/Station-Manager/types"
+)
+
+// errSessionExpired marks HamQTH session errors that can be resolved by logging in again.
+var errSessionExpired = session.ErrExpired
+
+// requestAndSetSessionID logs in to HamQTH with the configured credentials and assigns
+// the returned session id to the service instance.
+func (s *Service) requestAndSetSessionID(ctx context.Context) error {
+	const op errors.Op = "hamqth.Service.requestAndSetSessionID"
+
+	body, err := s.get(ctx, url.Values{
+		"u": {s.Config.Username},
+		"p": {s.Config.Password},
+	})
+	if err != nil {
+		return err
+	}
+
+	var db Database
+	if err = xml.Unmarshal(body, &db); err != nil {
+		return errors.New(op).Err(upstream.Tag(upstream.ErrMalformedResponse, err)).Msg("Failed to unmarshal XML")
+	}
+
+	if e := strings.TrimSpace(db.Session.Error); e != "" {
+		// At login an API-level error means the credentials were refused.
+		return errors.New(op).Err(upstream.ErrUnauthorized).Msgf("HamQTH returned error: %s", e)
+	}
+	id := strings.TrimSpace(db.Session.SessionID)
+	if id == "" {
+		return errors.New(op).Err(upstream.ErrMalformedResponse).Msg("HamQTH returned missing session id")
+	}
+
+	s.session.Set(id)
+
+	return nil
+}
+
+// withSession runs fn with the current session id. If fn fails because the session has
+// expired, the session is renewed once and fn is retried with the new id.
+func (s *Service) withSession(ctx context.Context, fn func(id string) error) error {
+	return s.session.Do(ctx, fn, func(ctx context.Context) error {
+		s.LoggerService.InfoWith().Msg("HamQTH session expired, logging in again")
+		return s.requestAndSetSessionID(ctx)
+	})
+}
+
+// get performs a GET against the HamQTH XML interface, retrying transient failures as
+// configured by Retry. While the circuit breaker is open it fails straight away.
+func (s *Service) get(ctx context.Context, params url.Values) ([]byte, error) {
+	return guard.Do(ctx, s.upstream(), func(ctx context.Context, attempt int) ([]byte, error) {
+		return s.getOnce(ctx, params, attempt)
+	})
+}
+
+// BreakerState returns the state of the circuit breaker in front of HamQTH, or
+// breaker.Disabled if the provider is disabled in its config.
+func (s *Service) BreakerState() breaker.State {
+	if s.Config != nil && !s.Config.Enabled {
+		return breaker.Disabled
+	}
+	return s.circuit.State()
+}
+
+// upstream describes HamQTH to the guard that retries its requests.
+func (s *Service) upstream() guard.Upstream {
+	return guard.Upstream{Name: "HamQTH", Breaker: s.circuit, Retry: s.Retry, Logger: s.LoggerService}
+}
+
+// getOnce performs a single GET against the HamQTH XML interface with the given query parameters,
+// returning the raw response body. attempt numbers the request for the Observer.
+func (s *Service) getOnce(ctx context.Context, params url.Values, attempt int) ([]byte, error) {
+	const op errors.Op = "hamqth.Service.getOnce"
+
+	u, err := url.Parse(s.Config.URL)
+	if err != nil {
+		return nil, errors.New(op).Err(err).Msg("invalid HamQTH base URL")
+	}
+
+	q := u.Query()
+	for k, v := range params {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+
+	if err = s.limiter.Wait(ctx); err != nil {
+		return nil, errors.New(op).Err(err).Msg("Request held back by the rate limiter")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, errors.New(op).Err(err).Msg("Failed to create HTTP GET request")
+	}
+
+	req.Header.Set("User-Agent", s.Config.UserAgent)
+	req.Header.Set("Accept", "application/xml")
+
+	observed := observer.Request(s.Observer, ServiceName, params.Get("callsign"), attempt, req, "u", "p")
+	resp, err := s.client.Do(req)
+	observed(resp, err)
+	if err != nil {
+		return nil, errors.New(op).Err(upstream.Unreachable(ctx, err)).Msg("Failed to perform HTTP GET request")
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	if rle := upstream.RateLimitFromResponse(resp); rle != nil {
+		s.limiter.Pause(rle.RetryAfter)
+		return nil, errors.New(op).Err(rle).Msgf("HamQTH rate limit exceeded (HTTP %d)", resp.StatusCode)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return nil, errors.New(op).Err(&upstream.StatusError{StatusCode: resp.StatusCode, Body: string(b)}).Msgf("HamQTH returned unexpected status %d: %s", resp.StatusCode, string(b))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.New(op).Err(upstream.Unreachable(ctx, err)).Msg("Failed to read response body")
+	}
+
+	return body, nil
+}
+
+func (s *Service) unmarshalResponse(body []byte) (types.ContactedStation, error) {
+	const op errors.Op = "hamqth.Service.unmarshalResponse"
+
+	var (
+		station types.ContactedStation
+		db      Database
+	)
+
+	if err := xml.Unmarshal(body, &db); err != nil {
+		return station, errors.New(op).Err(upstream.Tag(upstream.ErrMalformedResponse, err)).Msg("failed to unmarshal HamQTH XML response")
+	}
+
+	sessionErr := strings.TrimSpace(db.Session.Error)
+	if sessionErr != "" {
+		lower := strings.ToLower(sessionErr)
+		errBuilder := errors.New(op).Msg(sessionErr)
+		if strings.Contains(lower, "not found") {
+			errBuilder = errBuilder.Err(errors.ErrNotFound)
+		} else if strings.Contains(lower, "session does not exist or expired") {
+			errBuilder = errBuilder.Err(errSessionExpired)
+		}
+		return station, errBuilder
+	}
+
+	sr := db.Search
+	trim := func(v string) string {
+		return strings.TrimSpace(v)
+	}
+	joinParts := func(parts ...string) string {
+		var cleaned []string
+		for _, part := range parts {
+			if part = trim(part); part != "" {
+				cleaned = append(cleaned, part)
+			}
+		}
+		return strings.Join(cleaned, ", ")
+	}
+	buildName := func() string {
+		if v := trim(sr.AdrName); v != "" {
+			return v
+		}
+		return trim(sr.Nick)
+	}
+
+	call := strings.ToUpper(trim(sr.Callsign))
+	if call == "" {
+		return station, errors.New(op).Err(errors.ErrNotFound).Msg("callsign not present in HamQTH response")
+	}
+
+	country := trim(sr.Country)
+	if country == "" {
+		country = trim(sr.AdrCountry)
+	}
+
+	station.Call = call
+	station.Name = buildName()
+	station.Address = joinParts(sr.AdrStreet1, sr.AdrStreet2, sr.AdrStreet3, sr.AdrCity, sr.AdrZip, sr.AdrCountry)
+	station.QTH = trim(sr.QTH)
+	station.Country = country
+	station.Cont = strings.ToUpper(trim(sr.Continent))
+	station.Gridsquare = strings.ToUpper(trim(sr.Grid))
+	station.CQZ = trim(sr.Cq)
+	station.ITUZ = trim(sr.Itu)
+	station.DXCC = trim(sr.Adif)
+	station.Email = trim(sr.Email)
+	station.Web = trim(sr.Web)
+	station.Lat = trim(sr.Latitude)
+	station.Lon = trim(sr.Longitude)
+	station.Iota = strings.ToUpper(trim(sr.Iota))
+
+	return station, nil
+}
+
+func (s *Service) validateConfig(op errors.Op) error {
+	if s.Config == nil {
+		return errors.New(op).Msg("service config is not set")
+	}
+
+	// Don't check the config it the service is not enabled
+	if !s.Config.Enabled {
+		return nil
+	}
+
+	s.Config.URL = strings.TrimSpace(s.Config.URL)
+	if s.Config.URL == "" {
+		return errors.New(op).Msg("lookup service URL cannot be empty")
+	}
+
+	u, err := url.Parse(s.Config.URL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return errors.New(op).Err(err).Msg("lookup service URL is invalid")
+	}
+
+	s.Config.UserAgent = strings.TrimSpace(s.Config.UserAgent)
+	if s.Config.UserAgent == "" {
+		return errors.New(op).Msg("lookup service user agent cannot be empty")
+	}
+
+	if s.Config.HttpTimeoutSec <= 0 {
+		return errors.New(op).Msg("lookup service timeout must be greater than zero")
+	}
+
+	if strings.TrimSpace(s.Config.Username) == "" {
+		return errors.New(op).Msg("HamQTH lookup service username cannot be empty")
+	}
+
+	if s.Config.Password == "" {
+		return errors.New(op).Msg("HamQTH lookup service password cannot be empty")
+	}
+
+	return nil
+}
@@ -0,0 +1,237 @@
+package hamqth
+
+import (
+	"context"
+	stderr "errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Station-Manager/config"
+	"github.com/Station-Manager/errors"
+	"github.com/Station-Manager/logging"
+	"github.com/Station-Manager/lookup/breaker"
+	"github.com/Station-Manager/lookup/internal/calls"
+	"github.com/Station-Manager/lookup/internal/coalesce"
+	"github.com/Station-Manager/lookup/internal/session"
+	"github.com/Station-Manager/lookup/internal/upstream"
+	"github.com/Station-Manager/lookup/metrics"
+	"github.com/Station-Manager/lookup/observer"
+	"github.com/Station-Manager/lookup/ratelimit"
+	"github.com/Station-Manager/lookup/retry"
+	"github.com/Station-Manager/lookup/transport"
+	"github.com/Station-Manager/types"
+)
+
+const (
+	// ServiceName identifies the HamQTH lookup configuration and provider.
+	ServiceName = "hamqthlookupservice"
+)
+
+// DefaultRateLimit is applied when Service.RateLimit is left at its zero value. HamQTH
+// is a free, volunteer-run service without a published request rate, so it gets the
+// same modest default as QRZ.com.
+var DefaultRateLimit = ratelimit.Config{Rate: 2, Burst: 5}
+
+type Service struct {
+	ConfigService *config.Service  `di.inject:"configservice"`
+	LoggerService *logging.Service `di.inject:"loggingservice"`
+	Config        *types.LookupConfig
+	client        *http.Client
+
+	// Transport configures the HTTP client; see package transport. It is ignored when a
+	// client is passed to NewService.
+	Transport transport.Config
+
+	// RateLimit throttles requests, logins included; the zero value selects
+	// DefaultRateLimit.
+	RateLimit ratelimit.Config
+	limiter   *ratelimit.Limiter
+
+	// Retry governs retries of transient failures; see package retry.
+	Retry retry.Policy
+
+	// Breaker configures the circuit breaker; see package breaker.
+	Breaker breaker.Config
+	circuit *breaker.Breaker
+
+	// Metrics, if set, records every lookup under the provider label ServiceName.
+	Metrics metrics.Recorder
+
+	// Observer, if set, is told about every lookup and every request sent to HamQTH,
+	// logins included.
+	Observer observer.Observer
+
+	// inflight coalesces concurrent lookups of the same callsign into one request.
+	inflight coalesce.Group[string, types.ContactedStation]
+
+	isInitialized atomic.Bool
+	initOnce      sync.Once
+
+	session session.Session
+}
+
+// NewService returns a HamQTH lookup service with the provided dependencies. The
+// config.Service is optional if you supply Config directly. The client can be
+// overridden for testing; otherwise it will be created during Initialize.
+func NewService(logger *logging.Service, cfgSvc *config.Service, cfg *types.LookupConfig, client *http.Client) *Service {
+	return &Service{
+		LoggerService: logger,
+		ConfigService: cfgSvc,
+		Config:        cfg,
+		client:        client,
+	}
+}
+
+// Initialize initializes the Service instance by setting up required dependencies and configurations.
+func (s *Service) Initialize() error {
+	const op errors.Op = "hamqth.Service.Initialize"
+	if s.isInitialized.Load() {
+		return nil
+	}
+
+	var initErr error
+	s.initOnce.Do(func() {
+		if s.LoggerService == nil {
+			initErr = errors.New(op).Msg("logger service has not been set/injected")
+			return
+		}
+
+		if s.Config == nil {
+			if s.ConfigService == nil {
+				initErr = errors.New(op).Msg("application config has not been set/injected")
+				return
+			}
+
+			cfg, err := s.ConfigService.LookupServiceConfig(ServiceName)
+			if err != nil {
+				initErr = errors.New(op).Err(err).Msg("getting lookup service config")
+				return
+			}
+			s.Config = &cfg
+		}
+
+		if err := s.validateConfig(op); err != nil {
+			initErr = err
+			return
+		}
+
+		if s.RateLimit == (ratelimit.Config{}) {
+			s.RateLimit = DefaultRateLimit
+		}
+		s.limiter = ratelimit.New(s.RateLimit)
+		s.circuit = breaker.New(s.Breaker)
+
+		if !s.Config.Enabled {
+			s.LoggerService.InfoWith().Msg("HamQTH callsign lookup is disabled in the config")
+		} else {
+			if s.client == nil {
+				client, err := s.Transport.NewClient(s.Config.HttpTimeoutSec * time.Second)
+				if err != nil {
+					initErr = errors.New(op).Err(err).Msg("invalid HTTP transport settings")
+					return
+				}
+				s.client = client
+			}
+			if err := s.requestAndSetSessionID(context.Background()); err != nil {
+				initErr = err
+				return
+			}
+		}
+
+		s.isInitialized.Store(true)
+	})
+
+	return initErr
+}
+
+// Lookup retrieves information about a contacted station by its callsign.
+// It uses the default context and returns the station details or an error.
+func (s *Service) Lookup(callsign string) (types.ContactedStation, error) {
+	return s.LookupWithContext(context.Background(), callsign)
+}
+
+// LookupWithContext retrieves information about a contacted station based on the provided callsign and context.
+// An expired session is renewed transparently and the request retried once.
+func (s *Service) LookupWithContext(ctx context.Context, callsign string) (types.ContactedStation, error) {
+	ctx, observed := observer.Start(ctx, s.Observer, ServiceName, callsign)
+	done := metrics.Start(s.Metrics, ServiceName)
+	station, err := s.lookup(ctx, callsign)
+	done(err)
+	observed(err)
+	return station, err
+}
+
+func (s *Service) lookup(ctx context.Context, callsign string) (types.ContactedStation, error) {
+	const op errors.Op = "hamqth.Service.LookupWithContext"
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	emptyRetVal := types.ContactedStation{}
+	if !s.isInitialized.Load() {
+		return emptyRetVal, errors.New(op).Msg("service is not initialized")
+	}
+	if s.Config == nil {
+		return emptyRetVal, errors.New(op).Msg("service config is not set")
+	}
+
+	callsign = strings.TrimSpace(callsign)
+
+	// This check is here because if the client is disabled, the HTTP client will not be initialized
+	if !s.Config.Enabled {
+		s.LoggerService.InfoWith().Msg("HamQTH callsign lookup is disabled in the config")
+		return types.ContactedStation{Call: callsign}, errors.New(op).Err(upstream.ErrDisabled).Msg("HamQTH callsign lookup is disabled in the config")
+	}
+
+	if s.client == nil {
+		return emptyRetVal, errors.New(op).Msg("http client is not configured")
+	}
+
+	if callsign == "" {
+		return emptyRetVal, errors.New(op).Err(upstream.ErrInvalidCallsign).Msg("callsign cannot be empty")
+	}
+
+	// Invalid input is rejected before it costs a request. Callbook records are keyed by
+	// the home call, so portable and reciprocal forms are reduced to it before querying.
+	cs, err := calls.Validate(callsign)
+	if err != nil {
+		return emptyRetVal, errors.New(op).Err(err).Msg("invalid callsign")
+	}
+	callsign = cs.HomeCall
+
+	if err = s.limiter.Admit(ctx); err != nil {
+		return emptyRetVal, errors.New(op).Err(err).Msg("HamQTH is held back by the rate limiter")
+	}
+
+	// Concurrent lookups of the same call share one request; each caller still returns
+	// as soon as its own context is done.
+	station, _, err := s.inflight.Do(ctx, callsign, func(ctx context.Context) (types.ContactedStation, error) {
+		var station types.ContactedStation
+		err := s.withSession(ctx, func(id string) error {
+			body, err := s.get(ctx, url.Values{
+				"id":       {id},
+				"callsign": {callsign},
+				"prg":      {s.Config.UserAgent},
+			})
+			if err != nil {
+				return err
+			}
+			station, err = s.unmarshalResponse(body)
+			return err
+		})
+		return station, err
+	})
+	if err != nil {
+		if stderr.Is(err, errors.ErrNotFound) {
+			s.LoggerService.InfoWith().Str("callsign", callsign).Msg("Callsign not found in HamQTH database")
+			return types.ContactedStation{Call: callsign}, errors.New(op).Err(err).Msgf("%s not found in HamQTH database", callsign)
+		}
+		return emptyRetVal, errors.New(op).Errorf("HamQTH callsign lookup failed: %w", err)
+	}
+
+	return station, nil
+}
@@ -0,0 +1,165 @@
+package hamqth
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	smerrors "github.com/Station-Manager/errors"
+	"github.com/Station-Manager/logging"
+	"github.com/Station-Manager/lookup/observer"
+	"github.com/Station-Manager/types"
+)
+
+const searchXML = `<?xml version="1.0"?>
+<HamQTH version="2.8" xmlns="https://www.hamqth.com">
+<search>
+<callsign>ok2cqr</callsign>
+<nick>Petr</nick>
+<qth>Neratovice</qth>
+<country>Czech Republic</country>
+<adif>503</adif>
+<itu>28</itu>
+<cq>15</cq>
+<grid>jo70gg</grid>
+<adr_name>Petr Hlozek</adr_name>
+<adr_street1>17. listopadu 1065</adr_street1>
+<adr_city>Neratovice</adr_city>
+<adr_zip>27711</adr_zip>
+<adr_country>Czech Republic</adr_country>
+<iota>eu-001</iota>
+<email>petr@ok2cqr.com</email>
+<web>https://www.ok2cqr.com</web>
+<latitude>50.07</latitude>
+<longitude>14.42</longitude>
+<continent>EU</continent>
+</search>
+</HamQTH>`
+
+func TestService_unmarshalResponse(t *testing.T) {
+	s := &Service{}
+
+	got, err := s.unmarshalResponse([]byte(searchXML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := types.ContactedStation{
+		Call:       "OK2CQR",
+		Name:       "Petr Hlozek",
+		Address:    "17. listopadu 1065, Neratovice, 27711, Czech Republic",
+		QTH:        "Neratovice",
+		Country:    "Czech Republic",
+		Cont:       "EU",
+		Gridsquare: "JO70GG",
+		CQZ:        "15",
+		ITUZ:       "28",
+		DXCC:       "503",
+		Email:      "petr@ok2cqr.com",
+		Web:        "https://www.ok2cqr.com",
+		Lat:        "50.07",
+		Lon:        "14.42",
+		Iota:       "EU-001",
+	}
+	if got != want {
+		t.Fatalf("unexpected station: got %#v want %#v", got, want)
+	}
+}
+
+func TestService_unmarshalResponse_Errors(t *testing.T) {
+	s := &Service{}
+
+	_, err := s.unmarshalResponse([]byte(`<HamQTH version="2.8"><session><error>Callsign not found</error></session></HamQTH>`))
+	if !stderrors.Is(err, smerrors.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	_, err = s.unmarshalResponse([]byte(`<HamQTH version="2.8"><session><error>Session does not exist or expired</error></session></HamQTH>`))
+	if !stderrors.Is(err, errSessionExpired) {
+		t.Fatalf("expected errSessionExpired, got %v", err)
+	}
+}
+
+func TestService_Initialize_MissingCredentials(t *testing.T) {
+	cfg := &types.LookupConfig{Enabled: true, URL: "https://www.hamqth.com/xml.php", UserAgent: "test", HttpTimeoutSec: 5}
+	s := NewService(&logging.Service{}, nil, cfg, nil)
+
+	if err := s.Initialize(); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+}
+
+func TestService_LookupWithContext_RenewsExpiredSession(t *testing.T) {
+	var logins atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("u") != "" {
+			n := logins.Add(1)
+			_, _ = fmt.Fprintf(w, `<HamQTH version="2.8"><session><session_id>id%d</session_id></session></HamQTH>`, n)
+			return
+		}
+		// Only the second session is accepted, forcing one renewal.
+		if q.Get("id") != "id2" {
+			_, _ = w.Write([]byte(`<HamQTH version="2.8"><session><error>Session does not exist or expired</error></session></HamQTH>`))
+			return
+		}
+		if q.Get("prg") != "test" {
+			t.Errorf("expected program name to be sent, got %q", q.Get("prg"))
+		}
+		_, _ = w.Write([]byte(searchXML))
+	}))
+	defer ts.Close()
+
+	cfg := &types.LookupConfig{Enabled: true, URL: ts.URL, UserAgent: "test", HttpTimeoutSec: 5, Username: "ok2cqr", Password: "secret"}
+	s := NewService(&logging.Service{}, nil, cfg, ts.Client())
+	if err := s.Initialize(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	station, err := s.Lookup("OK2CQR")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if station.Call != "OK2CQR" {
+		t.Fatalf("unexpected station: %#v", station)
+	}
+	if got := logins.Load(); got != 2 {
+		t.Fatalf("expected initial login plus one renewal, got %d logins", got)
+	}
+}
+
+func TestService_Initialize_ObserverNeverSeesCredentials(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<HamQTH version="2.8"><session><session_id>id1</session_id></session></HamQTH>`))
+	}))
+	defer ts.Close()
+
+	var seen []string
+	cfg := &types.LookupConfig{Enabled: true, URL: ts.URL, UserAgent: "test", HttpTimeoutSec: 5, Username: "ok2cqr", Password: "secret"}
+	s := NewService(&logging.Service{}, nil, cfg, ts.Client())
+	s.Observer = observer.Funcs{
+		HTTPRequest: func(_ context.Context, _ observer.Event, req *http.Request) {
+			seen = append(seen, req.URL.String())
+		},
+		HTTPResponse: func(_ context.Context, _ observer.Event, resp *http.Response, _ error) {
+			seen = append(seen, resp.Request.URL.String())
+		},
+	}
+
+	if err := s.Initialize(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(seen) != 2 {
+		t.Fatalf("expected the login to be observed, got %v", seen)
+	}
+	for _, u := range seen {
+		if strings.Contains(u, "secret") || strings.Contains(u, "ok2cqr") {
+			t.Fatalf("observer saw credentials: %s", u)
+		}
+	}
+}
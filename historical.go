@@ -0,0 +1,53 @@
+package lookup
+
+import (
+	"context"
+	"time"
+
+	"github.com/Station-Manager/types"
+)
+
+// HistoricalProvider is implemented by providers whose data carries validity ranges and
+// can therefore resolve a callsign as it was assigned at a given moment (deleted
+// entities, reissued prefixes, dated DXpedition exceptions).
+type HistoricalProvider interface {
+	Provider
+	LookupAt(ctx context.Context, callsign string, at time.Time) (types.Country, error)
+}
+
+// historicalWrapper is implemented by the package's wrappers (CachedProvider, Chain,
+// ObservedProvider). They satisfy HistoricalProvider whatever they wrap, so they report
+// whether the wrapped providers can resolve by date, and per lookup whether the answer
+// was period-correct.
+type historicalWrapper interface {
+	historical() bool
+	lookupAt(ctx context.Context, callsign string, at time.Time) (types.Country, bool, error)
+}
+
+// LookupAt resolves callsign as of at using p. Providers that implement
+// HistoricalProvider answer for that date; any other provider falls back to its current
+// assignment via LookupWithContext, which is the best an online service can offer. The
+// returned bool reports whether the answer is period-correct. Wrappers around a
+// HistoricalProvider pass the date through.
+func LookupAt(ctx context.Context, p Provider, callsign string, at time.Time) (types.Country, bool, error) {
+	if !at.IsZero() {
+		if w, ok := p.(historicalWrapper); ok {
+			return w.lookupAt(ctx, callsign, at)
+		}
+		if hp, ok := p.(HistoricalProvider); ok {
+			country, err := hp.LookupAt(ctx, callsign, at)
+			return country, true, err
+		}
+	}
+	country, err := p.LookupWithContext(ctx, callsign)
+	return country, false, err
+}
+
+// isHistorical reports whether p resolves callsigns by date.
+func isHistorical(p Provider) bool {
+	if w, ok := p.(historicalWrapper); ok {
+		return w.historical()
+	}
+	_, ok := p.(HistoricalProvider)
+	return ok
+}
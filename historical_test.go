@@ -0,0 +1,52 @@
+package lookup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Station-Manager/types"
+)
+
+type currentOnlyProvider struct{}
+
+func (currentOnlyProvider) Initialize() error { return nil }
+func (p currentOnlyProvider) Lookup(callsign string) (types.Country, error) {
+	return p.LookupWithContext(context.Background(), callsign)
+}
+func (currentOnlyProvider) LookupWithContext(_ context.Context, _ string) (types.Country, error) {
+	return types.Country{Name: "current"}, nil
+}
+
+type datedProvider struct {
+	currentOnlyProvider
+	gotAt time.Time
+}
+
+func (p *datedProvider) LookupAt(_ context.Context, _ string, at time.Time) (types.Country, error) {
+	p.gotAt = at
+	return types.Country{Name: "historical"}, nil
+}
+
+func TestLookupAt_HistoricalProvider(t *testing.T) {
+	p := &datedProvider{}
+	at := time.Date(1993, time.March, 14, 0, 0, 0, 0, time.UTC)
+
+	country, periodCorrect, err := LookupAt(context.Background(), p, "K1ABC", at)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !periodCorrect || country.Name != "historical" || !p.gotAt.Equal(at) {
+		t.Fatalf("expected dated lookup, got %#v (periodCorrect=%v)", country, periodCorrect)
+	}
+}
+
+func TestLookupAt_FallsBackForOnlineProviders(t *testing.T) {
+	country, periodCorrect, err := LookupAt(context.Background(), currentOnlyProvider{}, "K1ABC", time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if periodCorrect || country.Name != "current" {
+		t.Fatalf("expected current-assignment fallback, got %#v (periodCorrect=%v)", country, periodCorrect)
+	}
+}
@@ -0,0 +1,286 @@
+// Package calls parses amateur radio callsigns, including the portable, mobile and
+// reciprocal-licence forms (VP2E/K1ABC, K1ABC/VE3, DL1XX/P, G4ABC/MM, W1AW/QRP). It is
+// shared by the provider subpackages and re-exported by the root lookup package.
+package calls
+
+import (
+	"strings"
+
+	"github.com/Station-Manager/errors"
+)
+
+// Indicator classifies the suffix appended to a callsign.
+type Indicator int
+
+const (
+	IndicatorNone         Indicator = iota
+	IndicatorPortable               // /P or /A
+	IndicatorMobile                 // /M
+	IndicatorMaritime               // /MM
+	IndicatorAeronautical           // /AM
+	IndicatorQRP                    // /QRP or /QRPP
+	IndicatorAreaDigit              // /0 - /9
+	IndicatorOther                  // any other non-prefix suffix, e.g. /B or /LH
+	IndicatorMountAthos             // /A after a Greek call (SV2ASP/A)
+)
+
+// mountAthosPrefix is the DXCC prefix of Mount Athos, which Greek stations operating
+// from there sign as /A. Elsewhere /A means portable.
+const mountAthosPrefix = "SV/A"
+
+// greekPrefixes are the ITU prefix blocks allocated to Greece.
+var greekPrefixes = []string{"SV", "SW", "SX", "SY", "SZ", "J4"}
+
+// String returns a human-readable name for the indicator.
+func (i Indicator) String() string {
+	switch i {
+	case IndicatorPortable:
+		return "portable"
+	case IndicatorMobile:
+		return "mobile"
+	case IndicatorMaritime:
+		return "maritime mobile"
+	case IndicatorAeronautical:
+		return "aeronautical mobile"
+	case IndicatorQRP:
+		return "QRP"
+	case IndicatorAreaDigit:
+		return "area digit"
+	case IndicatorOther:
+		return "other"
+	case IndicatorMountAthos:
+		return "Mount Athos"
+	default:
+		return "none"
+	}
+}
+
+// suffixIndicators maps well-known suffixes to their indicator.
+var suffixIndicators = map[string]Indicator{
+	"P":    IndicatorPortable,
+	"A":    IndicatorPortable,
+	"M":    IndicatorMobile,
+	"MM":   IndicatorMaritime,
+	"AM":   IndicatorAeronautical,
+	"QRP":  IndicatorQRP,
+	"QRPP": IndicatorQRP,
+	"B":    IndicatorOther,
+	"LH":   IndicatorOther,
+	"R":    IndicatorOther,
+}
+
+// Callsign is a callsign split into its structural parts.
+type Callsign struct {
+	// Raw is the upper-cased, trimmed input.
+	Raw string
+	// HomeCall is the station's own callsign without any prefix or suffix.
+	HomeCall string
+	// OperatingPrefix is the prefix of the country being operated from when it differs
+	// from the home call's, whether written before (VP2E/K1ABC) or after (K1ABC/VE3).
+	OperatingPrefix string
+	// Suffix is the indicator text after the final '/', e.g. "P", "MM" or "7".
+	Suffix    string
+	Indicator Indicator
+}
+
+// Parse splits a callsign into home call, operating prefix and suffix. It only checks
+// structure (at most three '/'-separated, non-empty alphanumeric parts, and an operating
+// prefix shaped like one); it does not
+// validate the callsign against ITU allocations or structure; see Validate.
+func Parse(s string) (Callsign, error) {
+	const op errors.Op = "calls.Parse"
+
+	raw := strings.ToUpper(strings.TrimSpace(s))
+	if raw == "" {
+		return Callsign{}, invalid(op, s, "is empty")
+	}
+
+	parts := strings.Split(raw, "/")
+	if len(parts) > 3 {
+		return Callsign{}, invalid(op, s, "has too many '/' separated parts")
+	}
+	for _, p := range parts {
+		if p == "" || !isAlnum(p) {
+			return Callsign{}, invalid(op, s, "is malformed")
+		}
+	}
+
+	c := Callsign{Raw: raw}
+
+	// A trailing indicator is peeled off first; what remains is either a lone home
+	// call or a prefix/home-call pair in either order.
+	if len(parts) > 1 {
+		last := parts[len(parts)-1]
+		if ind, ok := indicatorFor(last); ok {
+			c.Suffix = last
+			c.Indicator = ind
+			parts = parts[:len(parts)-1]
+		} else if !hasDigit(last) && !prefixPattern.MatchString(last) {
+			// A word such as JOTA or LGT is neither a prefix nor a call; keep it as an
+			// unknown suffix rather than querying it as a prefix.
+			c.Suffix = last
+			c.Indicator = IndicatorOther
+			parts = parts[:len(parts)-1]
+		}
+	}
+
+	switch len(parts) {
+	case 1:
+		c.HomeCall = parts[0]
+	case 2:
+		first, second := parts[0], parts[1]
+		// The home call is the part shaped like one (W1AW/VK9X, N1A/VE3). Only when
+		// both are equally plausible does the longer part win, and on a tie the
+		// ITU-recommended PREFIX/CALL order.
+		firstScore, secondScore := homeCallScore(first), homeCallScore(second)
+		switch {
+		case firstScore > secondScore:
+			c.HomeCall, c.OperatingPrefix = first, second
+		case secondScore > firstScore:
+			c.OperatingPrefix, c.HomeCall = first, second
+		case len(first) > len(second):
+			c.HomeCall, c.OperatingPrefix = first, second
+		default:
+			c.OperatingPrefix, c.HomeCall = first, second
+		}
+	default:
+		return Callsign{}, invalid(op, s, "has more than one operating prefix")
+	}
+
+	if c.OperatingPrefix != "" && !prefixPattern.MatchString(c.OperatingPrefix) {
+		return Callsign{}, invalid(op, s, "%s is not shaped like a prefix", c.OperatingPrefix)
+	}
+
+	if !hasDigit(c.HomeCall) && !hasDigit(c.OperatingPrefix) {
+		return Callsign{}, invalid(op, s, "contains no digit")
+	}
+
+	if c.Suffix == "A" && c.OperatingPrefix == "" && isGreek(c.HomeCall) {
+		c.Indicator = IndicatorMountAthos
+	}
+
+	return c, nil
+}
+
+// Prefix returns the prefix of the home call: everything up to and including the digit
+// that precedes the trailing letters (K1ABC -> K1, 3DA0XYZ -> 3DA0, 2E0ABC -> 2E0).
+func (c Callsign) Prefix() string {
+	return prefixOf(c.HomeCall)
+}
+
+// EffectivePrefix returns the prefix that determines the DXCC entity the station is
+// operating from: the operating prefix when one is given, the home prefix with its
+// area digit replaced for /digit suffixes (K1ABC/7 -> K7), SV/A for Mount Athos
+// (SV2ASP/A), and the home prefix otherwise.
+func (c Callsign) EffectivePrefix() string {
+	if c.OperatingPrefix != "" {
+		return c.OperatingPrefix
+	}
+	if c.Indicator == IndicatorMountAthos {
+		return mountAthosPrefix
+	}
+	if c.Indicator == IndicatorAreaDigit {
+		return replaceAreaDigit(prefixOf(c.HomeCall), c.Suffix)
+	}
+	return prefixOf(c.HomeCall)
+}
+
+// LookupCall returns the string a prefix or entity resolver should be queried with. It
+// keeps the full home call where that is still meaningful, so exact-callsign exceptions
+// continue to apply: K1ABC/P -> K1ABC, K1ABC/7 -> K7ABC, VP2E/K1ABC -> VP2E,
+// SV2ASP/A -> SV/A.
+func (c Callsign) LookupCall() string {
+	if c.OperatingPrefix != "" {
+		return c.OperatingPrefix
+	}
+	if c.Indicator == IndicatorMountAthos {
+		return mountAthosPrefix
+	}
+	if c.Indicator == IndicatorAreaDigit {
+		prefix := prefixOf(c.HomeCall)
+		return replaceAreaDigit(prefix, c.Suffix) + c.HomeCall[len(prefix):]
+	}
+	return c.HomeCall
+}
+
+// HasNoEntity reports whether the station is maritime or aeronautical mobile and so does
+// not count for any DXCC entity.
+func (c Callsign) HasNoEntity() bool {
+	return c.Indicator == IndicatorMaritime || c.Indicator == IndicatorAeronautical
+}
+
+// indicatorFor classifies a trailing part. Parts that look like a prefix (letters with
+// or without a digit, other than the known suffixes) are not indicators.
+func indicatorFor(part string) (Indicator, bool) {
+	if ind, ok := suffixIndicators[part]; ok {
+		return ind, true
+	}
+	if len(part) == 1 && part[0] >= '0' && part[0] <= '9' {
+		return IndicatorAreaDigit, true
+	}
+	return IndicatorNone, false
+}
+
+// homeCallScore rates how much a part looks like a home call rather than a prefix: zero
+// unless it follows the home call structure (VE3, KH6 and DL are prefixes), otherwise
+// one more than the length of its trailing letters, so W1AW outranks VK9X.
+func homeCallScore(part string) int {
+	if !homeCallPattern.MatchString(part) {
+		return 0
+	}
+	return 1 + len(part) - len(prefixOf(part))
+}
+
+// isGreek reports whether call was issued by Greece.
+func isGreek(call string) bool {
+	for _, p := range greekPrefixes {
+		if strings.HasPrefix(call, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// prefixOf strips the trailing run of letters (the suffix) from a call.
+func prefixOf(call string) string {
+	end := len(call)
+	for end > 0 && isLetter(call[end-1]) {
+		end--
+	}
+	if end == 0 {
+		return call
+	}
+	return call[:end]
+}
+
+// replaceAreaDigit replaces the last digit of prefix with digit.
+func replaceAreaDigit(prefix, digit string) string {
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] >= '0' && prefix[i] <= '9' {
+			return prefix[:i] + digit + prefix[i+1:]
+		}
+	}
+	return prefix + digit
+}
+
+func isLetter(b byte) bool {
+	return b >= 'A' && b <= 'Z'
+}
+
+func isAlnum(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isLetter(s[i]) && (s[i] < '0' || s[i] > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func hasDigit(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= '0' && s[i] <= '9' {
+			return true
+		}
+	}
+	return false
+}
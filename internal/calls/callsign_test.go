@@ -0,0 +1,87 @@
+package calls
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in              string
+		home, opPrefix  string
+		suffix          string
+		indicator       Indicator
+		effectivePrefix string
+		lookupCall      string
+	}{
+		{"k1abc", "K1ABC", "", "", IndicatorNone, "K1", "K1ABC"},
+		{" 3DA0XYZ ", "3DA0XYZ", "", "", IndicatorNone, "3DA0", "3DA0XYZ"},
+		{"2E0ABC", "2E0ABC", "", "", IndicatorNone, "2E0", "2E0ABC"},
+		{"DL1XX/P", "DL1XX", "", "P", IndicatorPortable, "DL1", "DL1XX"},
+		{"W1AW/M", "W1AW", "", "M", IndicatorMobile, "W1", "W1AW"},
+		{"G4ABC/MM", "G4ABC", "", "MM", IndicatorMaritime, "G4", "G4ABC"},
+		{"N1XYZ/AM", "N1XYZ", "", "AM", IndicatorAeronautical, "N1", "N1XYZ"},
+		{"W1AW/QRP", "W1AW", "", "QRP", IndicatorQRP, "W1", "W1AW"},
+		{"K1ABC/7", "K1ABC", "", "7", IndicatorAreaDigit, "K7", "K7ABC"},
+		{"VP2E/K1ABC", "K1ABC", "VP2E", "", IndicatorNone, "VP2E", "VP2E"},
+		{"K1ABC/VE3", "K1ABC", "VE3", "", IndicatorNone, "VE3", "VE3"},
+		{"DL/K1ABC/P", "K1ABC", "DL", "P", IndicatorPortable, "DL", "DL"},
+		{"OH2BH/LH", "OH2BH", "", "LH", IndicatorOther, "OH2", "OH2BH"},
+		// Trailing words that are not prefixes are kept as unknown suffixes.
+		{"K1ABC/JOTA", "K1ABC", "", "JOTA", IndicatorOther, "K1", "K1ABC"},
+		{"W1AW/LGT", "W1AW", "", "LGT", IndicatorOther, "W1", "W1AW"},
+		{"DL/K1ABC/JOTA", "K1ABC", "DL", "JOTA", IndicatorOther, "DL", "DL"},
+		// Equal-length halves are told apart by structure, in either order.
+		{"W1AW/VK9X", "W1AW", "VK9X", "", IndicatorNone, "VK9X", "VK9X"},
+		{"VK9X/W1AW", "W1AW", "VK9X", "", IndicatorNone, "VK9X", "VK9X"},
+		{"N1A/VE3", "N1A", "VE3", "", IndicatorNone, "VE3", "VE3"},
+		{"VE3/N1A", "N1A", "VE3", "", IndicatorNone, "VE3", "VE3"},
+		{"K1A/KH6", "K1A", "KH6", "", IndicatorNone, "KH6", "KH6"},
+		{"KH6/K1A", "K1A", "KH6", "", IndicatorNone, "KH6", "KH6"},
+		{"G4ABC/DL1", "G4ABC", "DL1", "", IndicatorNone, "DL1", "DL1"},
+		// Equally plausible halves fall back to length, then PREFIX/CALL order.
+		{"VP2V/K1A", "VP2V", "K1A", "", IndicatorNone, "K1A", "K1A"},
+		{"K1B/W1A", "W1A", "K1B", "", IndicatorNone, "K1B", "K1B"},
+		// /A is Mount Athos after a Greek call and portable elsewhere.
+		{"SV2ASP/A", "SV2ASP", "", "A", IndicatorMountAthos, "SV/A", "SV/A"},
+		{"J42ABC/A", "J42ABC", "", "A", IndicatorMountAthos, "SV/A", "SV/A"},
+		{"DL1XX/A", "DL1XX", "", "A", IndicatorPortable, "DL1", "DL1XX"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			c, err := Parse(tt.in)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if c.HomeCall != tt.home || c.OperatingPrefix != tt.opPrefix || c.Suffix != tt.suffix || c.Indicator != tt.indicator {
+				t.Fatalf("unexpected parse: %#v", c)
+			}
+			if got := c.EffectivePrefix(); got != tt.effectivePrefix {
+				t.Fatalf("expected effective prefix %q, got %q", tt.effectivePrefix, got)
+			}
+			if got := c.LookupCall(); got != tt.lookupCall {
+				t.Fatalf("expected lookup call %q, got %q", tt.lookupCall, got)
+			}
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, in := range []string{"", "   ", "K1ABC//P", "/K1ABC", "A/B/C/D", "K1-ABC", "VP2E/DL/K1ABC", "TEST",
+		// Operating prefixes must be shaped like one.
+		"JOTA/K1ABC", "K1ABC/JOTA/P", "K1ABC/G4ABC"} {
+		if _, err := Parse(in); err == nil {
+			t.Fatalf("expected error for %q, got nil", in)
+		}
+	}
+}
+
+func TestCallsign_HasNoEntity(t *testing.T) {
+	for in, want := range map[string]bool{"G4ABC/MM": true, "N1XYZ/AM": true, "G4ABC/M": false, "G4ABC": false} {
+		c, err := Parse(in)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %v", in, err)
+		}
+		if got := c.HasNoEntity(); got != want {
+			t.Fatalf("%s: expected %v, got %v", in, want, got)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package calls
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/Station-Manager/errors"
+	"github.com/Station-Manager/lookup/internal/upstream"
+)
+
+// CallsignError reports a callsign rejected by Normalize, Parse or Validate. It matches
+// upstream.ErrInvalidCallsign via errors.Is.
+type CallsignError struct {
+	// Callsign is the input as given.
+	Callsign string
+	Reason   string
+}
+
+// Error implements the error interface.
+func (e *CallsignError) Error() string {
+	return fmt.Sprintf("invalid callsign %q: %s", e.Callsign, e.Reason)
+}
+
+// Is reports whether target is upstream.ErrInvalidCallsign.
+func (e *CallsignError) Is(target error) bool {
+	return target == upstream.ErrInvalidCallsign
+}
+
+// invalid returns a DetailedError for op wrapping a CallsignError.
+func invalid(op errors.Op, callsign, format string, args ...any) error {
+	cerr := &CallsignError{Callsign: callsign, Reason: fmt.Sprintf(format, args...)}
+	return errors.New(op).Err(cerr).Msg(cerr.Error())
+}
+
+// homeCallPattern is the ITU callsign structure (RR Article 19): a one- or two-character
+// prefix (letters, letter-digit or digit-letter, plus the three-character digit-letter
+// prefixes such as 3DA), one or more digits, and a suffix ending in a letter. Suffixes
+// and digit runs are allowed to be longer than the ITU minimum for special-event calls
+// (GB100RSGB, TM2024FRA).
+var homeCallPattern = regexp.MustCompile(`^(?:[A-Z]{1,2}|[A-Z][0-9]|[0-9][A-Z]{1,2})[0-9]{1,4}[A-Z0-9]{0,6}[A-Z]$`)
+
+// prefixPattern is the shape of an operating prefix: up to three characters and a digit,
+// optionally followed by one or two letters (VE3, 3DA0, KH6, VK9X, VP2V), or a bare one-
+// or two-letter prefix (F, DL). Parts such as JOTA or LGT do not match.
+var prefixPattern = regexp.MustCompile(`^(?:[A-Z0-9]{0,3}[0-9][A-Z]{0,2}|[A-Z]{1,2})$`)
+
+// maxCallLen bounds the home call; nothing allocated by the ITU is longer.
+const maxCallLen = 10
+
+// Normalize converts the ways a callsign is commonly mistyped or pasted into its plain
+// form: it upper-cases, turns full-width characters into their ASCII counterparts and a
+// slashed zero (Ø) into 0, accepts '\' as a separator and drops spaces around
+// separators. Anything still containing spaces or characters other than A-Z, 0-9 and
+// '/' is rejected.
+func Normalize(s string) (string, error) {
+	const op errors.Op = "calls.Normalize"
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		switch {
+		case r >= '！' && r <= '～':
+			// Full-width forms mirror ASCII 0x21-0x7E.
+			r -= 0xFEE0
+		case r == 'Ø' || r == 'ø' || r == '∅' || r == '⌀':
+			r = '0'
+		case r == '\\':
+			r = '/'
+		case unicode.IsSpace(r):
+			r = ' '
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+
+	parts := strings.Split(b.String(), "/")
+	for i, p := range parts {
+		parts[i] = strings.TrimSpace(p)
+	}
+	norm := strings.Join(parts, "/")
+
+	if norm == "" {
+		return "", invalid(op, s, "is empty")
+	}
+	if strings.Contains(norm, " ") {
+		return "", invalid(op, s, "contains spaces")
+	}
+	for _, r := range norm {
+		if !(r >= 'A' && r <= 'Z') && !(r >= '0' && r <= '9') && r != '/' {
+			return "", invalid(op, s, "contains invalid character %q", r)
+		}
+	}
+	return norm, nil
+}
+
+// Validate normalizes and parses s, then checks the home call and any operating prefix
+// against the ITU callsign structure. It rejects input such as "TEST" or "12345" that
+// cannot be a callsign, so that it never reaches an upstream service.
+func Validate(s string) (Callsign, error) {
+	const op errors.Op = "calls.Validate"
+
+	norm, err := Normalize(s)
+	if err != nil {
+		return Callsign{}, err
+	}
+	c, err := Parse(norm)
+	if err != nil {
+		return Callsign{}, err
+	}
+
+	if len(c.HomeCall) > maxCallLen || !homeCallPattern.MatchString(c.HomeCall) {
+		return Callsign{}, invalid(op, s, "%s does not follow the ITU callsign structure", c.HomeCall)
+	}
+	if c.OperatingPrefix != "" && (len(c.OperatingPrefix) > 6 || !hasLetter(c.OperatingPrefix)) {
+		return Callsign{}, invalid(op, s, "%s is not a valid operating prefix", c.OperatingPrefix)
+	}
+	return c, nil
+}
+
+func hasLetter(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if isLetter(s[i]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package calls
+
+import (
+	stderrors "errors"
+	"testing"
+
+	"github.com/Station-Manager/lookup/internal/upstream"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"k1abc":         "K1ABC",
+		"  dl1xx  ":     "DL1XX",
+		"MØABC":         "M0ABC",
+		"m∅abc/p":       "M0ABC/P",
+		"Ｋ１ＡＢＣ":         "K1ABC",
+		"Ｇ４ＡＢＣ／Ｐ":       "G4ABC/P",
+		"K1ABC / P":     "K1ABC/P",
+		"K1ABC\\P":      "K1ABC/P",
+		"VP2E /K1ABC\t": "VP2E/K1ABC",
+	}
+	for in, want := range tests {
+		got, err := Normalize(in)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", in, err)
+		}
+		if got != want {
+			t.Fatalf("%q: expected %q, got %q", in, want, got)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := []string{"K1ABC", "2E0ABC", "3DA0RU", "E51ABC", "4U1UN", "T88XX", "9A1A", "KH6ABC", "GB100RSGB", "TM2024FRA", "VP2E/K1ABC", "K1ABC/7", "MØABC/P", "N1A/VE3", "VE3/N1A", "K1A/KH6", "KH6/K1A", "W1AW/VK9X", "VK9X/W1AW"}
+	for _, in := range valid {
+		if _, err := Validate(in); err != nil {
+			t.Fatalf("%q: unexpected error: %v", in, err)
+		}
+	}
+
+	invalid := []string{"", "TEST", "12345", "K1 ABC", "K1ABC!", "K1", "ABC1", "K1ABC/12345678", "A1B2C3D4E5F6", "ÄB1CD"}
+	for _, in := range invalid {
+		_, err := Validate(in)
+		var cerr *CallsignError
+		if !stderrors.As(err, &cerr) || !stderrors.Is(err, upstream.ErrInvalidCallsign) {
+			t.Fatalf("%q: expected a CallsignError, got %v", in, err)
+		}
+		if cerr.Callsign != in {
+			t.Fatalf("%q: expected the error to carry the input, got %q", in, cerr.Callsign)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Package coalesce deduplicates concurrent identical calls. Unlike a plain singleflight,
+// every caller waits under its own context: a caller that gives up returns immediately,
+// and the shared call is only cancelled once every caller waiting on it has gone.
+package coalesce
+
+import (
+	"context"
+	"sync"
+)
+
+// Group coalesces calls by key. The zero value is ready to use.
+type Group[K comparable, V any] struct {
+	mu    sync.Mutex
+	calls map[K]*call[V]
+}
+
+type call[V any] struct {
+	done    chan struct{}
+	val     V
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// Do runs fn once for all concurrent callers using the same key and returns its result
+// to each of them. fn receives a context that carries the values of the first caller's
+// context but is only cancelled when every waiting caller has been cancelled. shared
+// reports whether the result was shared with, or obtained from, another caller.
+func (g *Group[K, V]) Do(ctx context.Context, key K, fn func(ctx context.Context) (V, error)) (v V, shared bool, err error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[K]*call[V])
+	}
+	c, ok := g.calls[key]
+	if ok {
+		c.waiters++
+	} else {
+		callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		c = &call[V]{done: make(chan struct{}), waiters: 1, cancel: cancel}
+		g.calls[key] = c
+		go g.run(callCtx, key, c, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		g.mu.Lock()
+		shared = ok || c.waiters > 1
+		g.mu.Unlock()
+		return c.val, shared, c.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		c.waiters--
+		if c.waiters == 0 {
+			// Nobody is left to receive the result; stop the upstream request and make
+			// sure later callers start afresh rather than joining a cancelled call.
+			c.cancel()
+			if g.calls[key] == c {
+				delete(g.calls, key)
+			}
+		}
+		g.mu.Unlock()
+		var zero V
+		return zero, ok, ctx.Err()
+	}
+}
+
+// InFlight returns the number of distinct calls currently running.
+func (g *Group[K, V]) InFlight() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.calls)
+}
+
+func (g *Group[K, V]) run(ctx context.Context, key K, c *call[V], fn func(ctx context.Context) (V, error)) {
+	defer c.cancel()
+	c.val, c.err = fn(ctx)
+
+	g.mu.Lock()
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+	g.mu.Unlock()
+	close(c.done)
+}
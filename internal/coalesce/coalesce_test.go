@@ -0,0 +1,123 @@
+package coalesce
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGroup_SharesConcurrentCalls(t *testing.T) {
+	var g Group[string, int]
+	var runs atomic.Int32
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	results := make(chan int, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, _, err := g.Do(context.Background(), "K1ABC", func(context.Context) (int, error) {
+				runs.Add(1)
+				<-release
+				return 42, nil
+			})
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			results <- v
+		}()
+	}
+
+	// Let every caller join before the shared call completes.
+	for deadline := time.Now().Add(time.Second); g.waitersFor("K1ABC") < 5 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	close(results)
+
+	if got := runs.Load(); got != 1 {
+		t.Fatalf("expected a single shared call, got %d", got)
+	}
+	for v := range results {
+		if v != 42 {
+			t.Fatalf("unexpected result %d", v)
+		}
+	}
+}
+
+func TestGroup_CallerCancellationIsIndependent(t *testing.T) {
+	var g Group[string, int]
+	release := make(chan struct{})
+	var upstreamCancelled atomic.Bool
+	fn := func(ctx context.Context) (int, error) {
+		select {
+		case <-release:
+			return 7, nil
+		case <-ctx.Done():
+			upstreamCancelled.Store(true)
+			return 0, ctx.Err()
+		}
+	}
+
+	patient := make(chan int, 1)
+	go func() {
+		v, _, _ := g.Do(context.Background(), "K1ABC", fn)
+		patient <- v
+	}()
+	for g.waitersFor("K1ABC") < 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := g.Do(ctx, "K1ABC", fn); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the cancelled caller to return context.Canceled, got %v", err)
+	}
+
+	close(release)
+	if v := <-patient; v != 7 {
+		t.Fatalf("expected the remaining caller to get the shared result, got %d", v)
+	}
+	if upstreamCancelled.Load() {
+		t.Fatalf("expected the shared call to keep running while a caller still waits")
+	}
+}
+
+func TestGroup_LastCallerCancelsUpstream(t *testing.T) {
+	var g Group[string, int]
+	cancelled := make(chan struct{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	_, _, err := g.Do(ctx, "K1ABC", func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		close(cancelled)
+		return 0, ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatalf("expected the shared call to be cancelled once no caller is waiting")
+	}
+}
+
+func (g *Group[K, V]) waitersFor(key K) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if c, ok := g.calls[key]; ok {
+		return c.waiters
+	}
+	return 0
+}
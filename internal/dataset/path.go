@@ -0,0 +1,20 @@
+// Package dataset holds helpers shared by the providers that resolve callsigns from a
+// local data file (offline cty.dat, Club Log cty.xml).
+package dataset
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Path returns the filesystem path configured in v, which may be a plain path or a
+// file:// URL.
+func Path(v string) string {
+	v = strings.TrimSpace(v)
+	if strings.HasPrefix(v, "file://") {
+		if u, err := url.Parse(v); err == nil {
+			return u.Path
+		}
+	}
+	return v
+}
@@ -0,0 +1,19 @@
+package dataset
+
+import "testing"
+
+func TestPath(t *testing.T) {
+	tests := map[string]string{
+		"testdata/cty.dat":              "testdata/cty.dat",
+		"  /var/lib/sm/cty.xml ":        "/var/lib/sm/cty.xml",
+		"file:///var/lib/sm/cty.dat":    "/var/lib/sm/cty.dat",
+		"file:///C:/Station/cty.dat":    "/C:/Station/cty.dat",
+		"file:///data/with%20space.dat": "/data/with space.dat",
+		"":                              "",
+	}
+	for in, want := range tests {
+		if got := Path(in); got != want {
+			t.Errorf("Path(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+// Package filelock provides advisory, whole-file locks used to coordinate access to
+// files shared between processes. Locks are held on an open *os.File and released by
+// Unlock or by closing the file.
+package filelock
+
+import "os"
+
+// Lock places an exclusive lock on f, blocking until it is available.
+func Lock(f *os.File) error {
+	return lock(f, true)
+}
+
+// RLock places a shared lock on f, blocking until it is available.
+func RLock(f *os.File) error {
+	return lock(f, false)
+}
+
+// Unlock releases a lock previously placed on f.
+func Unlock(f *os.File) error {
+	return unlock(f)
+}
@@ -0,0 +1,11 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package filelock
+
+import "os"
+
+// Platforms without flock(2) or LockFileEx get no inter-process locking; callers in the
+// same process are still serialised by their own mutexes.
+func lock(_ *os.File, _ bool) error { return nil }
+
+func unlock(_ *os.File) error { return nil }
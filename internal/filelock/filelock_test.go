@@ -0,0 +1,48 @@
+package filelock
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLock_ExcludesSecondHolder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+	a, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer a.Close()
+	b, err := os.OpenFile(path, os.O_RDWR, 0o644)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer b.Close()
+
+	if err = Lock(a); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		_ = Lock(b)
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatalf("expected second lock to block while the first is held")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err = Unlock(a); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	select {
+	case <-acquired:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected second lock to be acquired after unlock")
+	}
+	_ = Unlock(b)
+}
@@ -0,0 +1,67 @@
+// Package guard runs the upstream requests of the HTTP lookup providers under their
+// circuit breaker and retry policy, so the providers share one implementation of both.
+package guard
+
+import (
+	"context"
+	stderr "errors"
+
+	"github.com/Station-Manager/errors"
+	"github.com/Station-Manager/logging"
+	"github.com/Station-Manager/lookup/breaker"
+	"github.com/Station-Manager/lookup/retry"
+)
+
+// Upstream describes the service a provider talks to.
+type Upstream struct {
+	// Name is the service's display name (e.g. "QRZ.com") used in logs and errors.
+	Name string
+	// Breaker guards the service; a nil Breaker never trips.
+	Breaker *breaker.Breaker
+	// Retry is the provider's retry policy; a zero MaxAttempts selects
+	// retry.DefaultPolicy.
+	Retry retry.Policy
+	// Logger, if set, records retried and finally failed attempts.
+	Logger *logging.Service
+}
+
+// Do calls once until it succeeds or the retry policy gives up, numbering each attempt
+// from 1. While the circuit breaker is open it fails straight away.
+func Do[T any](ctx context.Context, u Upstream, once func(ctx context.Context, attempt int) (T, error)) (T, error) {
+	const op errors.Op = "guard.Do"
+	var v T
+	attempt := 0
+	err := u.Breaker.Do(ctx, func() error {
+		return u.policy().Do(ctx, func(ctx context.Context) error {
+			var err error
+			attempt++
+			v, err = once(ctx, attempt)
+			return err
+		}, u.logAttempt)
+	})
+	if stderr.Is(err, breaker.ErrOpen) {
+		return v, errors.New(op).Err(err).Msgf("%s is unavailable (circuit breaker open)", u.Name)
+	}
+	return v, err
+}
+
+func (u Upstream) policy() retry.Policy {
+	if u.Retry.MaxAttempts == 0 {
+		return retry.DefaultPolicy
+	}
+	return u.Retry
+}
+
+// logAttempt records a failed attempt that is being retried, or the final failure of a
+// request that was retried at least once.
+func (u Upstream) logAttempt(a retry.Attempt) {
+	if u.Logger == nil {
+		return
+	}
+	switch {
+	case a.Retrying:
+		u.Logger.WarnWith().Err(a.Err).Int("attempt", a.Number).Dur("retry_in", a.Delay).Msgf("%s request failed, retrying", u.Name)
+	case a.Number > 1:
+		u.Logger.ErrorWith().Err(a.Err).Int("attempts", a.Number).Msgf("%s request failed, giving up", u.Name)
+	}
+}
@@ -0,0 +1,61 @@
+package guard
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Station-Manager/logging"
+	"github.com/Station-Manager/lookup/breaker"
+	"github.com/Station-Manager/lookup/internal/upstream"
+	"github.com/Station-Manager/lookup/retry"
+)
+
+func TestDo_RetriesAndNumbersAttempts(t *testing.T) {
+	u := Upstream{
+		Name:   "Test",
+		Retry:  retry.Policy{MaxAttempts: 3, BaseDelay: time.Millisecond, RetryableStatus: []int{http.StatusBadGateway}},
+		Logger: &logging.Service{},
+	}
+
+	var attempts []int
+	v, err := Do(context.Background(), u, func(_ context.Context, attempt int) (string, error) {
+		attempts = append(attempts, attempt)
+		if attempt < 3 {
+			return "", &upstream.StatusError{StatusCode: http.StatusBadGateway}
+		}
+		return "ok", nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v != "ok" || len(attempts) != 3 || attempts[2] != 3 {
+		t.Fatalf("expected success on attempt 3, got %q after %v", v, attempts)
+	}
+}
+
+func TestDo_FailsFastWhileBreakerOpen(t *testing.T) {
+	u := Upstream{
+		Name:    "Test",
+		Breaker: breaker.New(breaker.Config{FailureThreshold: 1, OpenFor: time.Minute}),
+		Retry:   retry.Policy{MaxAttempts: 1},
+	}
+
+	calls := 0
+	fail := func(context.Context, int) (int, error) {
+		calls++
+		return 0, &upstream.StatusError{StatusCode: http.StatusServiceUnavailable}
+	}
+	if _, err := Do(context.Background(), u, fail); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+	_, err := Do(context.Background(), u, fail)
+	if !errors.Is(err, breaker.ErrOpen) {
+		t.Fatalf("expected breaker.ErrOpen, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected the open breaker to skip the request, got %d calls", calls)
+	}
+}
@@ -0,0 +1,95 @@
+// Package session keeps the login session of a callbook provider (QRZ.com, HamQTH) and
+// renews it when the upstream reports it expired. Renewal is single-flight: callers that
+// arrive while a login is in flight wait for its outcome instead of logging in again.
+package session
+
+import (
+	"context"
+	stderr "errors"
+	"sync"
+
+	"github.com/Station-Manager/errors"
+)
+
+// ErrExpired marks upstream errors that can be resolved by logging in again.
+var ErrExpired = stderr.New("session expired")
+
+// Session holds the token of a login session. The zero value has no token and is ready
+// to use.
+type Session struct {
+	mu       sync.Mutex
+	token    string
+	renewing chan struct{}
+	renewErr error
+}
+
+// Token returns the token in use.
+func (s *Session) Token() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.token
+}
+
+// Set replaces the token in use.
+func (s *Session) Set(token string) {
+	s.mu.Lock()
+	s.token = token
+	s.mu.Unlock()
+}
+
+// Renew calls login to replace stale, which login stores with Set. Only one login runs
+// at a time: callers arriving while a renewal is in flight wait for its outcome rather
+// than issuing their own, and callers holding an already-replaced token return
+// immediately.
+func (s *Session) Renew(ctx context.Context, stale string, login func(context.Context) error) error {
+	s.mu.Lock()
+	if s.token != stale {
+		s.mu.Unlock()
+		return nil
+	}
+	if s.renewing != nil {
+		done := s.renewing
+		s.mu.Unlock()
+		select {
+		case <-done:
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			return s.renewErr
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	done := make(chan struct{})
+	s.renewing = done
+	s.mu.Unlock()
+
+	// The login is shared by every waiting caller, so it must not be cut short by the
+	// context of whichever caller happened to trigger it.
+	err := login(context.WithoutCancel(ctx))
+
+	s.mu.Lock()
+	s.renewErr = err
+	s.renewing = nil
+	s.mu.Unlock()
+	close(done)
+
+	return err
+}
+
+// Do runs fn with the current token. If fn fails with ErrExpired, the session is renewed
+// once with login and fn is run again with the new token.
+func (s *Session) Do(ctx context.Context, fn func(token string) error, login func(context.Context) error) error {
+	const op errors.Op = "session.Session.Do"
+
+	token := s.Token()
+	err := fn(token)
+	if !stderr.Is(err, ErrExpired) {
+		return err
+	}
+
+	if rErr := s.Renew(ctx, token, login); rErr != nil {
+		return errors.New(op).Err(rErr).Msg("renewing session")
+	}
+
+	return fn(s.Token())
+}
@@ -0,0 +1,116 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSession_DoRenewsExpiredToken(t *testing.T) {
+	var s Session
+	s.Set("stale")
+
+	var logins atomic.Int32
+	login := func(context.Context) error {
+		logins.Add(1)
+		s.Set("fresh")
+		return nil
+	}
+
+	var tokens []string
+	err := s.Do(context.Background(), func(token string) error {
+		tokens = append(tokens, token)
+		if token == "stale" {
+			return ErrExpired
+		}
+		return nil
+	}, login)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tokens) != 2 || tokens[1] != "fresh" {
+		t.Fatalf("expected a retry with the fresh token, got %v", tokens)
+	}
+	if got := logins.Load(); got != 1 {
+		t.Fatalf("expected one login, got %d", got)
+	}
+}
+
+func TestSession_DoPassesOtherErrorsThrough(t *testing.T) {
+	var s Session
+	want := errors.New("not found")
+
+	err := s.Do(context.Background(), func(string) error { return want }, func(context.Context) error {
+		t.Fatalf("unexpected login")
+		return nil
+	})
+	if !errors.Is(err, want) {
+		t.Fatalf("expected %v, got %v", want, err)
+	}
+}
+
+func TestSession_DoReportsLoginFailure(t *testing.T) {
+	var s Session
+	refused := errors.New("refused")
+
+	err := s.Do(context.Background(), func(string) error { return ErrExpired }, func(context.Context) error { return refused })
+	if !errors.Is(err, refused) {
+		t.Fatalf("expected %v, got %v", refused, err)
+	}
+}
+
+func TestSession_RenewLogsInOnceForConcurrentCallers(t *testing.T) {
+	var s Session
+	s.Set("stale")
+
+	var logins atomic.Int32
+	login := func(context.Context) error {
+		logins.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		s.Set("fresh")
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.Renew(context.Background(), "stale", login); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := logins.Load(); got != 1 {
+		t.Fatalf("expected a single shared login, got %d", got)
+	}
+	if got := s.Token(); got != "fresh" {
+		t.Fatalf("expected the fresh token, got %q", got)
+	}
+}
+
+func TestSession_RenewWaiterHonoursOwnContext(t *testing.T) {
+	var s Session
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go func() {
+		_ = s.Renew(context.Background(), "", func(context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := s.Renew(ctx, "", func(context.Context) error { return nil }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+// Package upstream holds the errors the provider subpackages share: the sentinels every
+// provider reports its failures with, and the types describing failures of the remote
+// services they call. The root lookup package re-exports them.
+package upstream
+
+import (
+	"context"
+	stderr "errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Station-Manager/utils"
+)
+
+// Sentinels shared by every provider. Callers test for them with errors.Is; not-found
+// conditions use errors.ErrNotFound from the Station-Manager errors package.
+var (
+	// ErrDisabled is returned by a provider switched off through LookupConfig.Enabled.
+	ErrDisabled = stderr.New("provider disabled")
+	// ErrUnauthorized reports credentials rejected by the upstream.
+	ErrUnauthorized = stderr.New("unauthorized")
+	// ErrUnavailable reports an upstream that could not be reached, timed out or failed
+	// with a 5xx status.
+	ErrUnavailable = stderr.New("upstream unavailable")
+	// ErrInvalidCallsign reports a callsign rejected before any lookup was made.
+	ErrInvalidCallsign = stderr.New("invalid callsign")
+	// ErrMalformedResponse reports an upstream answer that could not be decoded.
+	ErrMalformedResponse = stderr.New("malformed response")
+)
+
+// kindError tags an error with one of the sentinels while keeping it as the cause.
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string        { return e.kind.Error() + ": " + e.err.Error() }
+func (e *kindError) Is(target error) bool { return target == e.kind }
+func (e *kindError) Unwrap() error        { return e.err }
+
+// Tag returns err marked so that errors.Is(err, kind) holds, with err itself still
+// reachable through errors.Is/As.
+func Tag(kind, err error) error {
+	if err == nil {
+		return kind
+	}
+	return &kindError{kind: kind, err: err}
+}
+
+// StatusError reports an unexpected HTTP status returned by an upstream service.
+type StatusError struct {
+	StatusCode int
+	// Body is the (possibly truncated) response body, for diagnostics.
+	Body string
+}
+
+// Error implements the error interface.
+func (e *StatusError) Error() string {
+	return "upstream returned HTTP status " + strconv.Itoa(e.StatusCode) + " " + http.StatusText(e.StatusCode)
+}
+
+// Is matches ErrUnavailable for 5xx statuses and ErrUnauthorized for 401 and 403.
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case ErrUnavailable:
+		return e.StatusCode >= http.StatusInternalServerError
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	}
+	return false
+}
+
+// Temporary reports whether the status indicates a transient server-side condition
+// (5xx, 429 Too Many Requests or 408 Request Timeout).
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusRequestTimeout
+}
+
+// ErrRateLimited is matched (via errors.Is) by every RateLimitError.
+var ErrRateLimited = stderr.New("rate limited")
+
+// RateLimitError reports that a request was not made, or was refused upstream, because
+// of rate limiting.
+type RateLimitError struct {
+	// RetryAfter is how long to wait before trying again; zero if unknown.
+	RetryAfter time.Duration
+	// StatusCode is the upstream status (429 or 503), or zero when the request was held
+	// back by the client-side limiter.
+	StatusCode int
+}
+
+// Error implements the error interface.
+func (e *RateLimitError) Error() string {
+	msg := "rate limited"
+	if e.StatusCode != 0 {
+		msg += " by upstream (HTTP " + strconv.Itoa(e.StatusCode) + ")"
+	}
+	if e.RetryAfter > 0 {
+		msg += ", retry after " + e.RetryAfter.String()
+	}
+	return msg
+}
+
+// Is reports whether target is ErrRateLimited.
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// Temporary always reports true: a rate limit lifts with time.
+func (e *RateLimitError) Temporary() bool {
+	return true
+}
+
+// DefaultRetryAfter is assumed when a 429 response carries no usable Retry-After header.
+const DefaultRetryAfter = 5 * time.Second
+
+// RateLimitFromResponse returns a RateLimitError if resp signals rate limiting: any 429,
+// or a 503 carrying a Retry-After header. It returns nil otherwise.
+func RateLimitFromResponse(resp *http.Response) *RateLimitError {
+	if resp == nil {
+		return nil
+	}
+	d, ok := ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		if !ok {
+			d = DefaultRetryAfter
+		}
+	case resp.StatusCode == http.StatusServiceUnavailable && ok:
+	default:
+		return nil
+	}
+	return &RateLimitError{RetryAfter: d, StatusCode: resp.StatusCode}
+}
+
+// ParseRetryAfter interprets a Retry-After header value, which is either a number of
+// seconds or an HTTP date.
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		d := at.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// IsConnectionFailure reports whether err means the upstream could not be reached or
+// did not answer in time: a network error, a timeout, or a connection dropped before the
+// response was complete (which surfaces as (unexpected) EOF).
+func IsConnectionFailure(err error) bool {
+	return stderr.Is(err, context.DeadlineExceeded) || stderr.Is(err, io.EOF) ||
+		stderr.Is(err, io.ErrUnexpectedEOF) || utils.IsNetworkError(err)
+}
+
+// Unreachable tags err, returned by an HTTP client for a request made with ctx, as
+// ErrUnavailable, unless the request failed only because ctx ended.
+func Unreachable(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return err
+	}
+	return Tag(ErrUnavailable, err)
+}
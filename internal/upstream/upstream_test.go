@@ -0,0 +1,46 @@
+package upstream
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{"120", 2 * time.Minute, true},
+		{" 0 ", 0, true},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second, true},
+		{"-5", 0, false},
+		{"soon", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseRetryAfter(tt.in, now)
+		if got != tt.want || ok != tt.ok {
+			t.Fatalf("ParseRetryAfter(%q) = %v, %v; want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRateLimitFromResponse(t *testing.T) {
+	resp := &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{}}
+	if RateLimitFromResponse(resp) != nil {
+		t.Fatalf("expected a 503 without Retry-After not to be treated as rate limiting")
+	}
+
+	resp.Header.Set("Retry-After", "7")
+	if rle := RateLimitFromResponse(resp); rle == nil || rle.RetryAfter != 7*time.Second {
+		t.Fatalf("unexpected result: %#v", rle)
+	}
+
+	resp = &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}
+	if rle := RateLimitFromResponse(resp); rle == nil || rle.RetryAfter != DefaultRetryAfter {
+		t.Fatalf("expected DefaultRetryAfter for a bare 429, got %#v", rle)
+	}
+}
@@ -0,0 +1,221 @@
+package lookup
+
+import (
+	"context"
+	stderr "errors"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/Station-Manager/errors"
+	"github.com/Station-Manager/types"
+)
+
+// Field names a field of the merged record. Values are the JSON names of the
+// corresponding types.ContactedStation fields, so any field of that struct can appear in
+// MergedRecord.Provenance; constants are provided for the fields both kinds of provider
+// can supply.
+type Field string
+
+const (
+	FieldCountry   Field = "country"
+	FieldContinent Field = "cont"
+	FieldCQZone    Field = "cqz"
+	FieldITUZone   Field = "ituz"
+)
+
+// Source selects which provider wins when both supply a field.
+type Source int
+
+const (
+	PreferCountry Source = iota
+	PreferStation
+)
+
+// DefaultPrecedence prefers the country provider for the entity name and continent and
+// the callbook for the zones, which callbooks record per station (e.g. the three CQ
+// zones of the USA) rather than per prefix.
+var DefaultPrecedence = map[Field]Source{
+	FieldCountry:   PreferCountry,
+	FieldContinent: PreferCountry,
+	FieldCQZone:    PreferStation,
+	FieldITUZone:   PreferStation,
+}
+
+// MergeConflict records a field both providers supplied with different values.
+type MergeConflict struct {
+	Field        Field
+	CountryValue string
+	StationValue string
+	// Chosen is the name of the provider whose value was kept.
+	Chosen string
+}
+
+// MergedRecord is a station record combined from a country and a station provider.
+type MergedRecord struct {
+	// Station holds the merged fields.
+	Station types.ContactedStation
+	// Country is the country provider's answer, unchanged.
+	Country types.Country
+	// Provenance maps every populated field of Station to the name of the provider that
+	// supplied it.
+	Provenance map[Field]string
+	Conflicts  []MergeConflict
+	// CountryErr and StationErr report a provider that failed while the other answered.
+	CountryErr error
+	StationErr error
+}
+
+// Merger runs a country (prefix/DXCC) provider and a station (callbook) provider
+// concurrently and merges their answers into one record. It is itself a
+// StationProvider, returning the merged station.
+type Merger struct {
+	countryName string
+	country     Provider
+	stationName string
+	station     StationProvider
+
+	countryInitErr error
+	stationInitErr error
+
+	// Precedence overrides DefaultPrecedence for individual fields.
+	Precedence map[Field]Source
+}
+
+// NewMerger returns a merger over the named providers. The names are used in
+// MergedRecord.Provenance and MergeConflict.Chosen.
+func NewMerger(countryName string, country Provider, stationName string, station StationProvider) *Merger {
+	return &Merger{countryName: countryName, country: country, stationName: stationName, station: station}
+}
+
+// Initialize initializes both providers. A provider that fails to initialize is left out
+// of later lookups; an error is returned only if both fail.
+func (m *Merger) Initialize() error {
+	const op errors.Op = "lookup.Merger.Initialize"
+	if m.country == nil || m.station == nil {
+		return errors.New(op).Msg("both a country and a station provider must be set")
+	}
+	m.countryInitErr = m.country.Initialize()
+	m.stationInitErr = m.station.Initialize()
+	if m.countryInitErr != nil && m.stationInitErr != nil {
+		return errors.New(op).Err(stderr.Join(m.countryInitErr, m.stationInitErr)).Msg("neither provider could be initialized")
+	}
+	return nil
+}
+
+// Lookup returns the merged station using the default context.
+func (m *Merger) Lookup(callsign string) (types.ContactedStation, error) {
+	return m.LookupWithContext(context.Background(), callsign)
+}
+
+// LookupWithContext returns the merged station.
+func (m *Merger) LookupWithContext(ctx context.Context, callsign string) (types.ContactedStation, error) {
+	record, err := m.LookupMerged(ctx, callsign)
+	return record.Station, err
+}
+
+// LookupMerged queries both providers concurrently and merges their answers. It fails
+// only when neither provider answered.
+func (m *Merger) LookupMerged(ctx context.Context, callsign string) (MergedRecord, error) {
+	const op errors.Op = "lookup.Merger.LookupMerged"
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if m.country == nil || m.station == nil {
+		return MergedRecord{}, errors.New(op).Msg("both a country and a station provider must be set")
+	}
+
+	var country types.Country
+	var station types.ContactedStation
+	countryErr, stationErr := m.countryInitErr, m.stationInitErr
+
+	var wg sync.WaitGroup
+	if countryErr == nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			country, countryErr = m.country.LookupWithContext(ctx, callsign)
+		}()
+	}
+	if stationErr == nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			station, stationErr = m.station.LookupWithContext(ctx, callsign)
+		}()
+	}
+	wg.Wait()
+
+	if countryErr != nil && stationErr != nil {
+		return MergedRecord{}, errors.New(op).Err(stderr.Join(countryErr, stationErr)).Msg("neither provider answered")
+	}
+
+	record := MergedRecord{CountryErr: countryErr, StationErr: stationErr, Provenance: make(map[Field]string)}
+	if countryErr == nil {
+		record.Country = country
+	}
+	if stationErr == nil {
+		record.Station = station
+		m.recordStationProvenance(&record)
+	}
+	m.mergeField(&record, FieldCountry, record.Country.Name, &record.Station.Country)
+	m.mergeField(&record, FieldContinent, record.Country.Continent, &record.Station.Cont)
+	m.mergeField(&record, FieldCQZone, record.Country.CQZone, &record.Station.CQZ)
+	m.mergeField(&record, FieldITUZone, record.Country.ITUZone, &record.Station.ITUZ)
+
+	if record.Station.Call == "" {
+		record.Station.Call = strings.ToUpper(strings.TrimSpace(callsign))
+	}
+
+	return record, nil
+}
+
+// recordStationProvenance attributes every populated string field of the station to
+// the station provider.
+func (m *Merger) recordStationProvenance(record *MergedRecord) {
+	v := reflect.ValueOf(record.Station)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if v.Field(i).Kind() != reflect.String || v.Field(i).String() == "" {
+			continue
+		}
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		record.Provenance[Field(name)] = m.stationName
+	}
+}
+
+// mergeField resolves a field both providers can supply, writing the winner into
+// *stationValue and recording provenance and any conflict.
+func (m *Merger) mergeField(record *MergedRecord, field Field, countryValue string, stationValue *string) {
+	countryValue = strings.TrimSpace(countryValue)
+	current := strings.TrimSpace(*stationValue)
+
+	switch {
+	case countryValue == "":
+		return
+	case current == "":
+		*stationValue = countryValue
+		record.Provenance[field] = m.countryName
+		return
+	case strings.EqualFold(countryValue, current):
+		return
+	}
+
+	conflict := MergeConflict{Field: field, CountryValue: countryValue, StationValue: current, Chosen: m.stationName}
+	if m.precedence(field) == PreferCountry {
+		*stationValue = countryValue
+		record.Provenance[field] = m.countryName
+		conflict.Chosen = m.countryName
+	}
+	record.Conflicts = append(record.Conflicts, conflict)
+}
+
+func (m *Merger) precedence(field Field) Source {
+	if src, ok := m.Precedence[field]; ok {
+		return src
+	}
+	return DefaultPrecedence[field]
+}
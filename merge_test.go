@@ -0,0 +1,101 @@
+package lookup
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+
+	"github.com/Station-Manager/types"
+)
+
+func TestMerger_LookupMerged(t *testing.T) {
+	country := funcProvider(func(context.Context, string) (types.Country, error) {
+		return types.Country{Name: "United States", Continent: "NA", CQZone: "5", ITUZone: "8"}, nil
+	})
+	station := funcStationProvider(func(_ context.Context, callsign string) (types.ContactedStation, error) {
+		return types.ContactedStation{Call: callsign, Name: "Fred", Gridsquare: "FN42", Country: "USA", CQZ: "4"}, nil
+	})
+	m := NewMerger("hamnut", country, "qrz", station)
+	if err := m.Initialize(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	record, err := m.LookupMerged(context.Background(), "K1ABC")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s := record.Station
+	if s.Country != "United States" || s.CQZ != "4" || s.Cont != "NA" || s.ITUZ != "8" || s.Gridsquare != "FN42" {
+		t.Fatalf("unexpected merged station: %#v", s)
+	}
+	want := map[Field]string{FieldCountry: "hamnut", FieldCQZone: "qrz", FieldContinent: "hamnut", FieldITUZone: "hamnut", "gridsquare": "qrz", "name": "qrz"}
+	for field, provider := range want {
+		if got := record.Provenance[field]; got != provider {
+			t.Fatalf("expected %s from %s, got %q", field, provider, got)
+		}
+	}
+	if len(record.Conflicts) != 2 {
+		t.Fatalf("expected country and CQ zone conflicts, got %#v", record.Conflicts)
+	}
+}
+
+func TestMerger_PrecedenceOverride(t *testing.T) {
+	country := funcProvider(func(context.Context, string) (types.Country, error) {
+		return types.Country{Name: "United States", CQZone: "5"}, nil
+	})
+	station := funcStationProvider(func(_ context.Context, callsign string) (types.ContactedStation, error) {
+		return types.ContactedStation{Call: callsign, CQZ: "4"}, nil
+	})
+	m := NewMerger("hamnut", country, "qrz", station)
+	m.Precedence = map[Field]Source{FieldCQZone: PreferCountry}
+
+	record, err := m.LookupMerged(context.Background(), "K1ABC")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if record.Station.CQZ != "5" || record.Provenance[FieldCQZone] != "hamnut" {
+		t.Fatalf("expected country provider to win CQ zone, got %#v", record)
+	}
+}
+
+func TestMerger_PartialFailure(t *testing.T) {
+	boom := stderrors.New("boom")
+	country := funcProvider(func(context.Context, string) (types.Country, error) {
+		return types.Country{Name: "United States"}, nil
+	})
+	station := funcStationProvider(func(context.Context, string) (types.ContactedStation, error) {
+		return types.ContactedStation{}, boom
+	})
+	m := NewMerger("hamnut", country, "qrz", station)
+
+	record, err := m.LookupMerged(context.Background(), "k1abc")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !stderrors.Is(record.StationErr, boom) || record.Station.Country != "United States" || record.Station.Call != "K1ABC" {
+		t.Fatalf("unexpected record: %#v", record)
+	}
+
+	failing := funcProvider(func(context.Context, string) (types.Country, error) { return types.Country{}, boom })
+	if _, err = NewMerger("hamnut", failing, "qrz", station).LookupMerged(context.Background(), "K1ABC"); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+}
+
+func TestMerger_DisabledCountryProvider(t *testing.T) {
+	disabled := funcProvider(func(context.Context, string) (types.Country, error) {
+		return types.Country{Name: "Unknown"}, ErrDisabled
+	})
+	station := funcStationProvider(func(_ context.Context, callsign string) (types.ContactedStation, error) {
+		return types.ContactedStation{Call: callsign, Country: "USA"}, nil
+	})
+
+	record, err := NewMerger("hamnut", disabled, "qrz", station).LookupMerged(context.Background(), "K1ABC")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !stderrors.Is(record.CountryErr, ErrDisabled) || record.Country != (types.Country{}) || record.Station.Country != "USA" {
+		t.Fatalf("expected the disabled provider's answer to be ignored, got %#v", record)
+	}
+}
@@ -0,0 +1,95 @@
+// Package metrics instruments lookups. Providers, caching wrappers and chains report to a
+// Recorder; Registry is an in-memory Recorder that renders its metrics in the
+// Prometheus text exposition format.
+package metrics
+
+import (
+	"context"
+	stderr "errors"
+	"time"
+
+	"github.com/Station-Manager/errors"
+	"github.com/Station-Manager/lookup/internal/upstream"
+)
+
+// Class is the outcome of a lookup, used as a metric label.
+type Class string
+
+const (
+	ClassOK              Class = "ok"
+	ClassNotFound        Class = "not_found"
+	ClassDisabled        Class = "disabled"
+	ClassInvalidCallsign Class = "invalid_callsign"
+	ClassUnauthorized    Class = "unauthorized"
+	ClassRateLimited     Class = "rate_limited"
+	ClassCanceled        Class = "canceled"
+	ClassUnavailable     Class = "unavailable"
+	ClassTimeout         Class = "timeout"
+	ClassMalformed       Class = "malformed_response"
+	ClassOther           Class = "other"
+)
+
+// Classify maps an error returned by a lookup to its Class using the shared provider
+// errors (see the root lookup package).
+func Classify(err error) Class {
+	switch {
+	case err == nil:
+		return ClassOK
+	case stderr.Is(err, errors.ErrNotFound):
+		return ClassNotFound
+	case stderr.Is(err, upstream.ErrDisabled):
+		return ClassDisabled
+	case stderr.Is(err, upstream.ErrInvalidCallsign):
+		return ClassInvalidCallsign
+	case stderr.Is(err, upstream.ErrUnauthorized):
+		return ClassUnauthorized
+	case stderr.Is(err, upstream.ErrRateLimited):
+		return ClassRateLimited
+	case stderr.Is(err, context.Canceled):
+		return ClassCanceled
+	case stderr.Is(err, upstream.ErrUnavailable):
+		return ClassUnavailable
+	case stderr.Is(err, context.DeadlineExceeded):
+		return ClassTimeout
+	case stderr.Is(err, upstream.ErrMalformedResponse):
+		return ClassMalformed
+	default:
+		return ClassOther
+	}
+}
+
+// CacheResult is how a caching wrapper answered a lookup.
+type CacheResult string
+
+const (
+	CacheHit         CacheResult = "hit"
+	CacheNegativeHit CacheResult = "negative_hit"
+	CacheStoreHit    CacheResult = "store_hit"
+	CacheMiss        CacheResult = "miss"
+)
+
+// Recorder receives lookup instrumentation. Implementations must be safe for concurrent
+// use and should not block.
+type Recorder interface {
+	// LookupStarted is called when provider begins a lookup.
+	LookupStarted(provider string)
+	// LookupFinished is called when the lookup ends, with its outcome and duration.
+	LookupFinished(provider string, class Class, d time.Duration)
+	// CacheLookup is called for every lookup answered through a caching wrapper.
+	CacheLookup(cache string, result CacheResult)
+	// Failover is called when a chain moves past provider because of an error of class.
+	Failover(chain, provider string, class Class)
+}
+
+// Start records the start of a lookup on r and returns the function that records its
+// end. A nil r records nothing.
+func Start(r Recorder, provider string) (done func(err error)) {
+	if r == nil {
+		return func(error) {}
+	}
+	r.LookupStarted(provider)
+	start := time.Now()
+	return func(err error) {
+		r.LookupFinished(provider, Classify(err), time.Since(start))
+	}
+}
@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	smerrors "github.com/Station-Manager/errors"
+	"github.com/Station-Manager/lookup/internal/upstream"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err  error
+		want Class
+	}{
+		{nil, ClassOK},
+		{smerrors.New("test").Err(smerrors.ErrNotFound).Msg("no such call"), ClassNotFound},
+		{upstream.ErrDisabled, ClassDisabled},
+		{&upstream.StatusError{StatusCode: 503}, ClassUnavailable},
+		{&upstream.StatusError{StatusCode: 401}, ClassUnauthorized},
+		{&upstream.RateLimitError{}, ClassRateLimited},
+		{context.Canceled, ClassCanceled},
+		{context.DeadlineExceeded, ClassTimeout},
+		{upstream.Tag(upstream.ErrMalformedResponse, smerrors.New("test").Msg("bad xml")), ClassMalformed},
+		{smerrors.New("test").Msg("boom"), ClassOther},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.err); got != tt.want {
+			t.Errorf("Classify(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestStart_NilRecorder(t *testing.T) {
+	// Must not panic.
+	Start(nil, "hamnut")(nil)
+}
+
+func TestRegistry_WritePrometheus(t *testing.T) {
+	r := NewRegistry(0.1, 1)
+
+	Start(r, "hamnut")(nil)
+	Start(r, "hamnut")(upstream.ErrDisabled)
+	r.LookupFinished("qrz", ClassOK, 2*time.Second) // without a start: in-flight goes negative
+	Start(r, `we"ird`)
+	r.CacheLookup("hamnut", CacheHit)
+	r.CacheLookup("hamnut", CacheHit)
+	r.CacheLookup("hamnut", CacheMiss)
+	r.Failover("chain", "hamnut", ClassUnavailable)
+
+	var b strings.Builder
+	if err := r.WritePrometheus(&b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := b.String()
+
+	for _, want := range []string{
+		"# TYPE lookup_requests_total counter\n",
+		`lookup_requests_total{provider="hamnut"} 2` + "\n",
+		`lookup_errors_total{provider="hamnut",class="disabled"} 1` + "\n",
+		`lookup_in_flight{provider="hamnut"} 0` + "\n",
+		`lookup_in_flight{provider="we\"ird"} 1` + "\n",
+		"# TYPE lookup_duration_seconds histogram\n",
+		`lookup_duration_seconds_bucket{provider="hamnut",le="0.1"} 2` + "\n",
+		`lookup_duration_seconds_bucket{provider="qrz",le="1"} 0` + "\n",
+		`lookup_duration_seconds_bucket{provider="qrz",le="+Inf"} 1` + "\n",
+		`lookup_duration_seconds_sum{provider="qrz"} 2` + "\n",
+		`lookup_duration_seconds_count{provider="hamnut"} 2` + "\n",
+		`lookup_cache_requests_total{cache="hamnut",result="hit"} 2` + "\n",
+		`lookup_cache_requests_total{cache="hamnut",result="miss"} 1` + "\n",
+		`lookup_chain_failovers_total{chain="chain",provider="hamnut",class="unavailable"} 1` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, `class="ok"`) {
+		t.Errorf("successful lookups must not be counted as errors:\n%s", out)
+	}
+	if strings.Index(out, `provider="hamnut"`) > strings.Index(out, `provider="qrz"`) {
+		t.Errorf("expected series sorted by label value:\n%s", out)
+	}
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	r := NewRegistry()
+	Start(r, "hamnut")(nil)
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected Content-Type: %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), `lookup_requests_total{provider="hamnut"} 1`) {
+		t.Fatalf("unexpected body:\n%s", rec.Body.String())
+	}
+}
@@ -0,0 +1,202 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are the latency histogram bounds, in seconds, used when NewRegistry is
+// given none. They span cached answers to a provider running into its HTTP timeout.
+var DefaultBuckets = []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Registry is an in-memory Recorder. Its metrics are:
+//
+//	lookup_requests_total{provider}                 counter
+//	lookup_errors_total{provider,class}             counter (every outcome but ok)
+//	lookup_in_flight{provider}                      gauge
+//	lookup_duration_seconds{provider}               histogram
+//	lookup_cache_requests_total{cache,result}       counter
+//	lookup_chain_failovers_total{chain,provider,class} counter
+type Registry struct {
+	buckets []float64
+
+	mu        sync.Mutex
+	requests  map[string]uint64
+	errors    map[[2]string]uint64
+	inFlight  map[string]int64
+	durations map[string]*histogram
+	cache     map[[2]string]uint64
+	failovers map[[3]string]uint64
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+// NewRegistry returns an empty registry using the given histogram bucket upper bounds in
+// seconds, or DefaultBuckets if none are given.
+func NewRegistry(buckets ...float64) *Registry {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &Registry{
+		buckets:   buckets,
+		requests:  make(map[string]uint64),
+		errors:    make(map[[2]string]uint64),
+		inFlight:  make(map[string]int64),
+		durations: make(map[string]*histogram),
+		cache:     make(map[[2]string]uint64),
+		failovers: make(map[[3]string]uint64),
+	}
+}
+
+// LookupStarted implements Recorder.
+func (r *Registry) LookupStarted(provider string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests[provider]++
+	r.inFlight[provider]++
+}
+
+// LookupFinished implements Recorder.
+func (r *Registry) LookupFinished(provider string, class Class, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.inFlight[provider]--
+	if class != ClassOK {
+		r.errors[[2]string{provider, string(class)}]++
+	}
+
+	h := r.durations[provider]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(r.buckets))}
+		r.durations[provider] = h
+	}
+	secs := d.Seconds()
+	if i := sort.SearchFloat64s(r.buckets, secs); i < len(r.buckets) {
+		h.counts[i]++
+	}
+	h.sum += secs
+	h.count++
+}
+
+// CacheLookup implements Recorder.
+func (r *Registry) CacheLookup(cache string, result CacheResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cache[[2]string{cache, string(result)}]++
+}
+
+// Failover implements Recorder.
+func (r *Registry) Failover(chain, provider string, class Class) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failovers[[3]string{chain, provider, string(class)}]++
+}
+
+// WritePrometheus writes every metric in the Prometheus text exposition format (version
+// 0.0.4), with series sorted by label values.
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+
+	header(bw, "lookup_requests_total", "counter", "Lookups started, by provider.")
+	for _, p := range sortedKeys(r.requests) {
+		fmt.Fprintf(bw, "lookup_requests_total{provider=%s} %d\n", quote(p), r.requests[p])
+	}
+
+	header(bw, "lookup_errors_total", "counter", "Lookups that did not return a result, by provider and error class.")
+	for _, k := range sortedKeys(r.errors) {
+		fmt.Fprintf(bw, "lookup_errors_total{provider=%s,class=%s} %d\n", quote(k[0]), quote(k[1]), r.errors[k])
+	}
+
+	header(bw, "lookup_in_flight", "gauge", "Lookups currently in progress, by provider.")
+	for _, p := range sortedKeys(r.inFlight) {
+		fmt.Fprintf(bw, "lookup_in_flight{provider=%s} %d\n", quote(p), r.inFlight[p])
+	}
+
+	header(bw, "lookup_duration_seconds", "histogram", "Lookup latency, by provider.")
+	for _, p := range sortedKeys(r.durations) {
+		h := r.durations[p]
+		var cumulative uint64
+		for i, le := range r.buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(bw, "lookup_duration_seconds_bucket{provider=%s,le=%s} %d\n", quote(p), quote(formatFloat(le)), cumulative)
+		}
+		fmt.Fprintf(bw, "lookup_duration_seconds_bucket{provider=%s,le=\"+Inf\"} %d\n", quote(p), h.count)
+		fmt.Fprintf(bw, "lookup_duration_seconds_sum{provider=%s} %s\n", quote(p), formatFloat(h.sum))
+		fmt.Fprintf(bw, "lookup_duration_seconds_count{provider=%s} %d\n", quote(p), h.count)
+	}
+
+	header(bw, "lookup_cache_requests_total", "counter", "Lookups answered through a caching wrapper, by cache and result.")
+	for _, k := range sortedKeys(r.cache) {
+		fmt.Fprintf(bw, "lookup_cache_requests_total{cache=%s,result=%s} %d\n", quote(k[0]), quote(k[1]), r.cache[k])
+	}
+
+	header(bw, "lookup_chain_failovers_total", "counter", "Providers a failover chain moved past, by chain, provider and error class.")
+	for _, k := range sortedKeys(r.failovers) {
+		fmt.Fprintf(bw, "lookup_chain_failovers_total{chain=%s,provider=%s,class=%s} %d\n", quote(k[0]), quote(k[1]), quote(k[2]), r.failovers[k])
+	}
+
+	return bw.Flush()
+}
+
+// Handler returns an http.Handler serving the metrics for a Prometheus scrape.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = r.WritePrometheus(w)
+	})
+}
+
+func header(w io.Writer, name, typ, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// quote renders a label value, escaping as the exposition format requires.
+func quote(v string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v) + `"`
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// labelKey is the key type of the registry's series maps: one value per label.
+type labelKey interface {
+	~string | ~[2]string | ~[3]string
+}
+
+// sortedKeys returns the keys of m ordered by their label values, first label first.
+func sortedKeys[K labelKey, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return joinLabels(keys[i]) < joinLabels(keys[j]) })
+	return keys
+}
+
+func joinLabels(k any) string {
+	switch k := k.(type) {
+	case [2]string:
+		return k[0] + "\x00" + k[1]
+	case [3]string:
+		return k[0] + "\x00" + k[1] + "\x00" + k[2]
+	default:
+		return fmt.Sprint(k)
+	}
+}
@@ -0,0 +1,111 @@
+package lookup
+
+import (
+	"context"
+	"time"
+
+	"github.com/Station-Manager/errors"
+	"github.com/Station-Manager/lookup/observer"
+	"github.com/Station-Manager/types"
+)
+
+// ObservedProvider is a Provider that reports each lookup of the wrapped provider to an
+// observer.Observer. It suits providers without an Observer field of their own (offline,
+// Club Log, custom providers); set the field on Hamnut, QRZ.com and HamQTH instead, which
+// also reports their HTTP requests.
+type ObservedProvider struct {
+	name     string
+	provider Provider
+	observer observer.Observer
+}
+
+// Observe wraps p so that its lookups are reported to o under the provider label name.
+func Observe(name string, p Provider, o observer.Observer) *ObservedProvider {
+	return &ObservedProvider{name: name, provider: p, observer: o}
+}
+
+// Initialize initializes the wrapped provider.
+func (o *ObservedProvider) Initialize() error {
+	const op errors.Op = "lookup.ObservedProvider.Initialize"
+	if o.provider == nil {
+		return errors.New(op).Msg("wrapped provider has not been set")
+	}
+	return o.provider.Initialize()
+}
+
+// Lookup resolves a callsign using the default context.
+func (o *ObservedProvider) Lookup(callsign string) (types.Country, error) {
+	return o.LookupWithContext(context.Background(), callsign)
+}
+
+// LookupWithContext resolves a callsign with the wrapped provider, reporting the lookup
+// to the observer.
+func (o *ObservedProvider) LookupWithContext(ctx context.Context, callsign string) (types.Country, error) {
+	if o.provider == nil {
+		return types.Country{}, errors.New("lookup.ObservedProvider.LookupWithContext").Msg("wrapped provider has not been set")
+	}
+	ctx, observed := observer.Start(ctx, o.observer, o.name, callsign)
+	country, err := o.provider.LookupWithContext(ctx, callsign)
+	observed(err)
+	return country, err
+}
+
+// LookupAt resolves callsign as of at with the wrapped provider (see lookup.LookupAt),
+// reporting the lookup to the observer.
+func (o *ObservedProvider) LookupAt(ctx context.Context, callsign string, at time.Time) (types.Country, error) {
+	country, _, err := o.lookupAt(ctx, callsign, at)
+	return country, err
+}
+
+func (o *ObservedProvider) historical() bool {
+	return o.provider != nil && isHistorical(o.provider)
+}
+
+func (o *ObservedProvider) lookupAt(ctx context.Context, callsign string, at time.Time) (types.Country, bool, error) {
+	if o.provider == nil {
+		return types.Country{}, false, errors.New("lookup.ObservedProvider.LookupAt").Msg("wrapped provider has not been set")
+	}
+	ctx, observed := observer.Start(ctx, o.observer, o.name, callsign)
+	country, periodCorrect, err := LookupAt(ctx, o.provider, callsign, at)
+	observed(err)
+	return country, periodCorrect, err
+}
+
+// ObservedStationProvider is ObservedProvider for a StationProvider.
+type ObservedStationProvider struct {
+	name     string
+	provider StationProvider
+	observer observer.Observer
+}
+
+// ObserveStations wraps p so that its lookups are reported to o under the provider label
+// name.
+func ObserveStations(name string, p StationProvider, o observer.Observer) *ObservedStationProvider {
+	return &ObservedStationProvider{name: name, provider: p, observer: o}
+}
+
+// Initialize initializes the wrapped provider.
+func (o *ObservedStationProvider) Initialize() error {
+	const op errors.Op = "lookup.ObservedStationProvider.Initialize"
+	if o.provider == nil {
+		return errors.New(op).Msg("wrapped provider has not been set")
+	}
+	return o.provider.Initialize()
+}
+
+// Lookup retrieves a station using the default context.
+func (o *ObservedStationProvider) Lookup(callsign string) (types.ContactedStation, error) {
+	return o.LookupWithContext(context.Background(), callsign)
+}
+
+// LookupWithContext retrieves a station with the wrapped provider, reporting the lookup
+// to the observer.
+func (o *ObservedStationProvider) LookupWithContext(ctx context.Context, callsign string) (types.ContactedStation, error) {
+	if o.provider == nil {
+		return types.ContactedStation{}, errors.New("lookup.ObservedStationProvider.LookupWithContext").Msg("wrapped provider has not been set")
+	}
+	ctx, observed := observer.Start(ctx, o.observer, o.name, callsign)
+	station, err := o.provider.LookupWithContext(ctx, callsign)
+	observed(err)
+	return station, err
+}
@@ -0,0 +1,87 @@
+package lookup
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+	"time"
+
+	smerrors "github.com/Station-Manager/errors"
+	"github.com/Station-Manager/lookup/observer"
+	"github.com/Station-Manager/types"
+)
+
+type ctxKey struct{}
+
+func TestObserve_ReportsLookups(t *testing.T) {
+	var events []string
+	var spanSeen bool
+	o := observer.Funcs{
+		Start: func(ctx context.Context, e observer.Event) context.Context {
+			events = append(events, "start "+e.Provider+" "+e.Callsign)
+			return context.WithValue(ctx, ctxKey{}, "span")
+		},
+		Result: func(ctx context.Context, e observer.Event) { events = append(events, "result") },
+		Error: func(ctx context.Context, e observer.Event, err error) {
+			if stderrors.Is(err, smerrors.ErrNotFound) {
+				events = append(events, "not found")
+			}
+		},
+	}
+
+	p := Observe("offline", funcProvider(func(ctx context.Context, callsign string) (types.Country, error) {
+		spanSeen = ctx.Value(ctxKey{}) == "span"
+		if callsign == "K1ABC" {
+			return types.Country{Name: "TestLand"}, nil
+		}
+		return types.Country{}, smerrors.New("test").Err(smerrors.ErrNotFound).Msg("no such call")
+	}), o)
+
+	if country, err := p.Lookup("K1ABC"); err != nil || country.Name != "TestLand" {
+		t.Fatalf("unexpected result: %#v %v", country, err)
+	}
+	if !spanSeen {
+		t.Fatalf("expected the wrapped provider to receive the context returned by OnStart")
+	}
+	if _, err := p.Lookup("ZZ9ZZ"); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+
+	want := []string{"start offline K1ABC", "result", "start offline ZZ9ZZ", "not found"}
+	if len(events) != len(want) {
+		t.Fatalf("unexpected events: %q", events)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Fatalf("unexpected events: %q", events)
+		}
+	}
+}
+
+func TestObserve_LookupAtPassesDateThrough(t *testing.T) {
+	at := time.Date(1993, time.March, 14, 0, 0, 0, 0, time.UTC)
+	var started bool
+	p := &datedProvider{}
+	o := Observe("clublog", p, observer.Funcs{
+		Start: func(ctx context.Context, e observer.Event) context.Context {
+			started = true
+			return ctx
+		},
+	})
+
+	country, periodCorrect, err := LookupAt(context.Background(), o, "K1ABC", at)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !periodCorrect || country.Name != "historical" || !p.gotAt.Equal(at) {
+		t.Fatalf("expected dated lookup, got %#v (periodCorrect=%v)", country, periodCorrect)
+	}
+	if !started {
+		t.Fatalf("expected the dated lookup to be observed")
+	}
+
+	country, periodCorrect, err = LookupAt(context.Background(), Observe("hamnut", currentOnlyProvider{}, nil), "K1ABC", at)
+	if err != nil || periodCorrect || country.Name != "current" {
+		t.Fatalf("expected current-assignment fallback, got %#v (periodCorrect=%v) %v", country, periodCorrect, err)
+	}
+}
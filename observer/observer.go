@@ -0,0 +1,209 @@
+// Package observer lets callers follow individual lookups, e.g. to open tracing spans or
+// feed a diagnostics panel. The HTTP providers (Hamnut, QRZ.com, HamQTH) report every
+// lookup and every upstream request to the Observer set on the service; lookup.Observe
+// attaches one to any other provider.
+package observer
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Event describes the lookup or request an Observer is told about.
+type Event struct {
+	// Provider is the service name of the provider, e.g. hamnut.ServiceName.
+	Provider string
+	// Callsign is the callsign being looked up. For HTTP events it is the value sent
+	// upstream (Hamnut is sent the prefix); it is empty for logins.
+	Callsign string
+	// Attempt numbers the tries of one upstream request, from 1, so retries (see
+	// retry.Policy) can be told apart. It is zero for OnStart, OnResult and OnError.
+	Attempt int
+	// Duration is the time since OnStart for OnResult and OnError, and since
+	// OnHTTPRequest for OnHTTPResponse. It is zero otherwise.
+	Duration time.Duration
+}
+
+// Observer is told about lookups as they happen. Its methods are called synchronously
+// on the lookup's goroutine, so they must be safe for concurrent use and should return
+// quickly.
+type Observer interface {
+	// OnStart is called when a lookup begins. The context it returns is used for the rest
+	// of the lookup, so a tracer can store its span there; return ctx if there is nothing
+	// to add.
+	OnStart(ctx context.Context, e Event) context.Context
+	// OnHTTPRequest is called before each request is sent to the upstream, retries
+	// included. It may add headers to req.
+	OnHTTPRequest(ctx context.Context, e Event, req *http.Request)
+	// OnHTTPResponse is called when the request completes. resp is nil if err is not;
+	// its body belongs to the provider and must not be read.
+	OnHTTPResponse(ctx context.Context, e Event, resp *http.Response, err error)
+	// OnResult is called when a lookup succeeds.
+	OnResult(ctx context.Context, e Event)
+	// OnError is called when a lookup fails, including not-found and disabled providers.
+	OnError(ctx context.Context, e Event, err error)
+}
+
+// Start reports the start of a lookup to o. It returns the context to continue the
+// lookup with and the function that reports its outcome. A nil o observes nothing.
+func Start(ctx context.Context, o Observer, provider, callsign string) (context.Context, func(err error)) {
+	if o == nil {
+		return ctx, func(error) {}
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	e := Event{Provider: provider, Callsign: callsign}
+	ctx = o.OnStart(ctx, e)
+	start := time.Now()
+	return ctx, func(err error) {
+		e.Duration = time.Since(start)
+		if err != nil {
+			o.OnError(ctx, e, err)
+			return
+		}
+		o.OnResult(ctx, e)
+	}
+}
+
+// Request reports an HTTP request to o just before it is sent and returns the function
+// that reports its response. A nil o observes nothing. The query parameters named in
+// redact, typically credentials, are replaced by Redacted in everything o is shown: the
+// request, the response's Request and the URL of a *url.Error.
+func Request(o Observer, provider, callsign string, attempt int, req *http.Request, redact ...string) func(resp *http.Response, err error) {
+	if o == nil {
+		return func(*http.Response, error) {}
+	}
+	e := Event{Provider: provider, Callsign: callsign, Attempt: attempt}
+	ctx := req.Context()
+	shown := redactRequest(req, redact)
+	o.OnHTTPRequest(ctx, e, shown)
+	start := time.Now()
+	return func(resp *http.Response, err error) {
+		e.Duration = time.Since(start)
+		if shown != req {
+			if resp != nil {
+				r := *resp
+				r.Request = shown
+				resp = &r
+			}
+			if ue, ok := err.(*url.Error); ok {
+				err = &url.Error{Op: ue.Op, URL: shown.URL.String(), Err: ue.Err}
+			}
+		}
+		o.OnHTTPResponse(ctx, e, resp, err)
+	}
+}
+
+// Redacted replaces the value of redacted query parameters.
+const Redacted = "REDACTED"
+
+// redactRequest returns req, or a copy of it with the named query parameters replaced if
+// it carries any of them.
+func redactRequest(req *http.Request, params []string) *http.Request {
+	if len(params) == 0 || req.URL == nil {
+		return req
+	}
+	q := req.URL.Query()
+	found := false
+	for _, p := range params {
+		if q.Has(p) {
+			q.Set(p, Redacted)
+			found = true
+		}
+	}
+	if !found {
+		return req
+	}
+	clone := req.Clone(req.Context())
+	clone.URL.RawQuery = q.Encode()
+	return clone
+}
+
+// Funcs is an Observer built from optional functions, for callers interested in only
+// some of the events. Nil fields are skipped.
+type Funcs struct {
+	Start        func(ctx context.Context, e Event) context.Context
+	HTTPRequest  func(ctx context.Context, e Event, req *http.Request)
+	HTTPResponse func(ctx context.Context, e Event, resp *http.Response, err error)
+	Result       func(ctx context.Context, e Event)
+	Error        func(ctx context.Context, e Event, err error)
+}
+
+// OnStart implements Observer.
+func (f Funcs) OnStart(ctx context.Context, e Event) context.Context {
+	if f.Start == nil {
+		return ctx
+	}
+	return f.Start(ctx, e)
+}
+
+// OnHTTPRequest implements Observer.
+func (f Funcs) OnHTTPRequest(ctx context.Context, e Event, req *http.Request) {
+	if f.HTTPRequest != nil {
+		f.HTTPRequest(ctx, e, req)
+	}
+}
+
+// OnHTTPResponse implements Observer.
+func (f Funcs) OnHTTPResponse(ctx context.Context, e Event, resp *http.Response, err error) {
+	if f.HTTPResponse != nil {
+		f.HTTPResponse(ctx, e, resp, err)
+	}
+}
+
+// OnResult implements Observer.
+func (f Funcs) OnResult(ctx context.Context, e Event) {
+	if f.Result != nil {
+		f.Result(ctx, e)
+	}
+}
+
+// OnError implements Observer.
+func (f Funcs) OnError(ctx context.Context, e Event, err error) {
+	if f.Error != nil {
+		f.Error(ctx, e, err)
+	}
+}
+
+// Multi returns an Observer that forwards every event to each of observers in turn, so
+// that e.g. a tracer and a diagnostics panel can watch the same provider. Contexts
+// returned by OnStart are chained.
+func Multi(observers ...Observer) Observer {
+	return multi(observers)
+}
+
+type multi []Observer
+
+func (m multi) OnStart(ctx context.Context, e Event) context.Context {
+	for _, o := range m {
+		ctx = o.OnStart(ctx, e)
+	}
+	return ctx
+}
+
+func (m multi) OnHTTPRequest(ctx context.Context, e Event, req *http.Request) {
+	for _, o := range m {
+		o.OnHTTPRequest(ctx, e, req)
+	}
+}
+
+func (m multi) OnHTTPResponse(ctx context.Context, e Event, resp *http.Response, err error) {
+	for _, o := range m {
+		o.OnHTTPResponse(ctx, e, resp, err)
+	}
+}
+
+func (m multi) OnResult(ctx context.Context, e Event) {
+	for _, o := range m {
+		o.OnResult(ctx, e)
+	}
+}
+
+func (m multi) OnError(ctx context.Context, e Event, err error) {
+	for _, o := range m {
+		o.OnError(ctx, e, err)
+	}
+}
@@ -0,0 +1,93 @@
+package observer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type key int
+
+func TestStart_NilObserver(t *testing.T) {
+	ctx := context.Background()
+	got, done := Start(ctx, nil, "hamnut", "K1ABC")
+	if got != ctx {
+		t.Fatalf("expected the context to be returned unchanged")
+	}
+	done(nil)
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.invalid", nil)
+	Request(nil, "hamnut", "K1", 1, req)(nil, errors.New("boom"))
+}
+
+func TestMulti_ChainsContextsAndForwardsEvents(t *testing.T) {
+	var got []string
+	record := func(name string, k key) Observer {
+		return Funcs{
+			Start: func(ctx context.Context, e Event) context.Context {
+				got = append(got, name+" start")
+				return context.WithValue(ctx, k, name)
+			},
+			Error: func(ctx context.Context, e Event, err error) {
+				// Each observer sees the values added by every OnStart.
+				if ctx.Value(key(1)) == nil || ctx.Value(key(2)) == nil {
+					t.Errorf("%s: missing context values", name)
+				}
+				if e.Duration < 0 || e.Provider != "qrz" || e.Callsign != "K1ABC" {
+					t.Errorf("%s: unexpected event %#v", name, e)
+				}
+				got = append(got, name+" error")
+			},
+		}
+	}
+
+	o := Multi(record("tracer", 1), record("panel", 2))
+	_, done := Start(context.Background(), o, "qrz", "K1ABC")
+	done(errors.New("boom"))
+
+	want := []string{"tracer start", "panel start", "tracer error", "panel error"}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected events: %q", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("unexpected events: %q", got)
+		}
+	}
+}
+
+func TestRequest_RedactsParameters(t *testing.T) {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "https://example.com/xml?username=tester&password=secret&agent=test", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var seen []string
+	o := Funcs{
+		HTTPRequest: func(_ context.Context, _ Event, r *http.Request) { seen = append(seen, r.URL.String()) },
+		HTTPResponse: func(_ context.Context, _ Event, resp *http.Response, err error) {
+			seen = append(seen, resp.Request.URL.String(), err.Error())
+		},
+	}
+
+	done := Request(o, "qrz", "", 1, req, "username", "password")
+	done(&http.Response{Request: req}, &url.Error{Op: "Get", URL: req.URL.String(), Err: errors.New("connection reset")})
+
+	if len(seen) != 3 {
+		t.Fatalf("expected three observations, got %v", seen)
+	}
+	for _, s := range seen {
+		if strings.Contains(s, "secret") || strings.Contains(s, "tester") {
+			t.Fatalf("observer saw credentials: %s", s)
+		}
+		if !strings.Contains(s, "agent=test") {
+			t.Fatalf("expected other parameters to be kept: %s", s)
+		}
+	}
+	if req.URL.Query().Get("password") != "secret" {
+		t.Fatalf("the request sent upstream must keep its credentials, got %s", req.URL)
+	}
+}
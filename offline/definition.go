@@ -0,0 +1,31 @@
+package offline
+
+// Entity is a DXCC (or WAE) entity as described by a cty.dat header line.
+type Entity struct {
+	Name          string
+	CQZone        int
+	ITUZone       int
+	Continent     string
+	Lat           float64 // degrees, north positive
+	Lon           float64 // degrees, east positive
+	UTCOffset     float64 // hours, east of UTC positive
+	PrimaryPrefix string
+	// WAEOnly marks entities that only count for the DARC WAEDC award (a leading '*'
+	// on the primary prefix in cty.dat).
+	WAEOnly bool
+}
+
+// Match is the result of resolving a callsign against a Dataset: the entity together
+// with any per-prefix or per-callsign overrides that applied.
+type Match struct {
+	Entity *Entity
+	// Prefix is the alias prefix (or full callsign for exact matches) that matched.
+	Prefix    string
+	Exact     bool
+	CQZone    int
+	ITUZone   int
+	Continent string
+	Lat       float64
+	Lon       float64
+	UTCOffset float64
+}
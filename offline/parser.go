@@ -0,0 +1,277 @@
+package offline
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/Station-Manager/errors"
+)
+
+// headerFields is the number of colon-terminated fields in a cty.dat entity header.
+const headerFields = 8
+
+// Dataset is a parsed cty.dat/BigCTY file indexed for longest-prefix matching.
+type Dataset struct {
+	Entities []*Entity
+
+	prefixes map[string]*Match
+	exact    map[string]*Match
+	// maxPrefixLen bounds the prefix search so lookups do not probe lengths that cannot match.
+	maxPrefixLen int
+}
+
+// ParseCTY parses AD1C's cty.dat (or the BigCTY superset) format. Each record is an
+// entity header of eight colon-terminated fields followed by a comma-separated alias
+// list terminated by ';'. Aliases prefixed with '=' are exact callsigns, and any alias
+// may carry (CQ) [ITU] <lat/lon> {continent} ~offset~ overrides.
+func ParseCTY(r io.Reader) (*Dataset, error) {
+	const op errors.Op = "offline.ParseCTY"
+
+	ds := &Dataset{
+		prefixes: make(map[string]*Match),
+		exact:    make(map[string]*Match),
+	}
+
+	var record strings.Builder
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		record.WriteString(text)
+		record.WriteByte(' ')
+
+		if !strings.HasSuffix(text, ";") {
+			continue
+		}
+		if err := ds.addRecord(record.String()); err != nil {
+			return nil, errors.New(op).Err(err).Msgf("invalid cty.dat record ending on line %d: %v", line, err)
+		}
+		record.Reset()
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.New(op).Err(err).Msg("reading cty.dat data")
+	}
+	if strings.TrimSpace(record.String()) != "" {
+		return nil, errors.New(op).Msg("cty.dat data ends with an unterminated record")
+	}
+	if len(ds.Entities) == 0 {
+		return nil, errors.New(op).Msg("cty.dat data contains no entities")
+	}
+
+	return ds, nil
+}
+
+// Resolve returns the best match for an upper-case callsign: an exact callsign entry
+// if there is one, otherwise the longest matching prefix.
+func (ds *Dataset) Resolve(call string) (*Match, bool) {
+	if m, ok := ds.exact[call]; ok {
+		return m, true
+	}
+	n := len(call)
+	if n > ds.maxPrefixLen {
+		n = ds.maxPrefixLen
+	}
+	for ; n > 0; n-- {
+		if m, ok := ds.prefixes[call[:n]]; ok {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+// addRecord parses a complete "header: aliases;" record.
+func (ds *Dataset) addRecord(record string) error {
+	record = strings.TrimSuffix(strings.TrimSpace(record), ";")
+
+	fields := make([]string, 0, headerFields)
+	rest := record
+	for i := 0; i < headerFields; i++ {
+		idx := strings.IndexByte(rest, ':')
+		if idx < 0 {
+			return errors.New("offline.Dataset.addRecord").Msgf("expected %d header fields, found %d", headerFields, i)
+		}
+		fields = append(fields, strings.TrimSpace(rest[:idx]))
+		rest = rest[idx+1:]
+	}
+
+	entity, err := parseHeader(fields)
+	if err != nil {
+		return err
+	}
+	ds.Entities = append(ds.Entities, entity)
+
+	// A primary prefix with a '/' (SV/A, Mount Athos) is a suffix rule that appears in no
+	// alias; register it so that callers resolving it directly find the entity.
+	if strings.Contains(entity.PrimaryPrefix, "/") {
+		m, err := parseAlias(entity, entity.PrimaryPrefix)
+		if err != nil {
+			return err
+		}
+		ds.prefixes[m.Prefix] = m
+		if len(m.Prefix) > ds.maxPrefixLen {
+			ds.maxPrefixLen = len(m.Prefix)
+		}
+	}
+
+	for _, alias := range strings.Split(rest, ",") {
+		alias = strings.Join(strings.Fields(alias), "")
+		if alias == "" {
+			continue
+		}
+		m, err := parseAlias(entity, alias)
+		if err != nil {
+			return err
+		}
+		if m.Exact {
+			ds.exact[m.Prefix] = m
+			continue
+		}
+		ds.prefixes[m.Prefix] = m
+		if len(m.Prefix) > ds.maxPrefixLen {
+			ds.maxPrefixLen = len(m.Prefix)
+		}
+	}
+
+	return nil
+}
+
+// parseHeader converts the eight header fields into an Entity. cty.dat gives longitude
+// and UTC offset with west positive; both are flipped to the usual east-positive sense.
+func parseHeader(f []string) (*Entity, error) {
+	const op errors.Op = "offline.parseHeader"
+
+	cq, err := strconv.Atoi(f[1])
+	if err != nil {
+		return nil, errors.New(op).Err(err).Msgf("invalid CQ zone %q", f[1])
+	}
+	itu, err := strconv.Atoi(f[2])
+	if err != nil {
+		return nil, errors.New(op).Err(err).Msgf("invalid ITU zone %q", f[2])
+	}
+	lat, err := strconv.ParseFloat(f[4], 64)
+	if err != nil {
+		return nil, errors.New(op).Err(err).Msgf("invalid latitude %q", f[4])
+	}
+	lon, err := strconv.ParseFloat(f[5], 64)
+	if err != nil {
+		return nil, errors.New(op).Err(err).Msgf("invalid longitude %q", f[5])
+	}
+	offset, err := strconv.ParseFloat(f[6], 64)
+	if err != nil {
+		return nil, errors.New(op).Err(err).Msgf("invalid UTC offset %q", f[6])
+	}
+
+	primary := strings.ToUpper(f[7])
+	waeOnly := strings.HasPrefix(primary, "*")
+
+	return &Entity{
+		Name:          f[0],
+		CQZone:        cq,
+		ITUZone:       itu,
+		Continent:     strings.ToUpper(f[3]),
+		Lat:           lat,
+		Lon:           -lon,
+		UTCOffset:     -offset,
+		PrimaryPrefix: strings.TrimPrefix(primary, "*"),
+		WAEOnly:       waeOnly,
+	}, nil
+}
+
+// parseAlias parses a single alias such as "=VP2EAA(8)[11]" or "KL7<61.2/149.9>~9~".
+func parseAlias(entity *Entity, alias string) (*Match, error) {
+	const op errors.Op = "offline.parseAlias"
+
+	m := &Match{
+		Entity:    entity,
+		CQZone:    entity.CQZone,
+		ITUZone:   entity.ITUZone,
+		Continent: entity.Continent,
+		Lat:       entity.Lat,
+		Lon:       entity.Lon,
+		UTCOffset: entity.UTCOffset,
+	}
+
+	if strings.HasPrefix(alias, "=") {
+		m.Exact = true
+		alias = alias[1:]
+	}
+
+	end := strings.IndexAny(alias, "([<{~")
+	if end < 0 {
+		end = len(alias)
+	}
+	m.Prefix = strings.ToUpper(alias[:end])
+	if m.Prefix == "" {
+		return nil, errors.New(op).Msgf("alias %q has no prefix", alias)
+	}
+
+	overrides := alias[end:]
+	for overrides != "" {
+		open := overrides[0]
+		var closeCh byte
+		switch open {
+		case '(':
+			closeCh = ')'
+		case '[':
+			closeCh = ']'
+		case '<':
+			closeCh = '>'
+		case '{':
+			closeCh = '}'
+		case '~':
+			closeCh = '~'
+		default:
+			return nil, errors.New(op).Msgf("unexpected character %q in alias %q", open, alias)
+		}
+		idx := strings.IndexByte(overrides[1:], closeCh)
+		if idx < 0 {
+			return nil, errors.New(op).Msgf("unterminated override in alias %q", alias)
+		}
+		value := overrides[1 : idx+1]
+		overrides = overrides[idx+2:]
+
+		if err := applyOverride(m, open, value); err != nil {
+			return nil, errors.New(op).Err(err).Msgf("invalid override in alias %q", alias)
+		}
+	}
+
+	return m, nil
+}
+
+// applyOverride applies a single override, identified by its opening delimiter.
+func applyOverride(m *Match, kind byte, value string) error {
+	var err error
+	switch kind {
+	case '(':
+		m.CQZone, err = strconv.Atoi(value)
+	case '[':
+		m.ITUZone, err = strconv.Atoi(value)
+	case '{':
+		m.Continent = strings.ToUpper(value)
+	case '~':
+		var offset float64
+		offset, err = strconv.ParseFloat(value, 64)
+		m.UTCOffset = -offset
+	case '<':
+		latStr, lonStr, ok := strings.Cut(value, "/")
+		if !ok {
+			return errors.New("offline.applyOverride").Msgf("expected lat/lon, got %q", value)
+		}
+		var lat, lon float64
+		if lat, err = strconv.ParseFloat(latStr, 64); err != nil {
+			return err
+		}
+		if lon, err = strconv.ParseFloat(lonStr, 64); err != nil {
+			return err
+		}
+		m.Lat, m.Lon = lat, -lon
+	}
+	return err
+}
@@ -0,0 +1,100 @@
+package offline
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func loadTestDataset(t *testing.T) *Dataset {
+	t.Helper()
+	f, err := os.Open("testdata/cty.dat")
+	if err != nil {
+		t.Fatalf("opening test data: %v", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	ds, err := ParseCTY(f)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return ds
+}
+
+func TestParseCTY_Entities(t *testing.T) {
+	ds := loadTestDataset(t)
+
+	if len(ds.Entities) != 8 {
+		t.Fatalf("expected 8 entities, got %d", len(ds.Entities))
+	}
+
+	us := ds.Entities[1]
+	if us.Name != "United States" || us.CQZone != 5 || us.ITUZone != 8 || us.Continent != "NA" || us.PrimaryPrefix != "K" {
+		t.Fatalf("unexpected entity: %#v", us)
+	}
+	if us.Lon != -91.67 || us.UTCOffset != -5 {
+		t.Fatalf("expected west-positive values to be flipped, got lon=%v offset=%v", us.Lon, us.UTCOffset)
+	}
+
+	sicily := ds.Entities[4]
+	if !sicily.WAEOnly || sicily.PrimaryPrefix != "IT9" {
+		t.Fatalf("expected WAE-only entity with primary prefix IT9, got %#v", sicily)
+	}
+}
+
+func TestDataset_Resolve(t *testing.T) {
+	ds := loadTestDataset(t)
+
+	tests := []struct {
+		call      string
+		entity    string
+		prefix    string
+		exact     bool
+		cq, itu   int
+		continent string
+		offset    float64
+	}{
+		{call: "K1ABC", entity: "United States", prefix: "K", cq: 5, itu: 8, continent: "NA", offset: -5},
+		{call: "KH6XYZ", entity: "Hawaii", prefix: "KH6", cq: 31, itu: 61, continent: "OC", offset: -10},
+		{call: "W6ABC", entity: "United States", prefix: "W6", cq: 3, itu: 6, continent: "NA", offset: -8},
+		{call: "AH2BW", entity: "United States", prefix: "AH2BW", exact: true, cq: 27, itu: 64, continent: "OC", offset: 10},
+		{call: "DL1XX", entity: "Fed. Rep. of Germany", prefix: "DL", cq: 14, itu: 28, continent: "EU", offset: 1},
+		{call: "IT9ABC", entity: "Sicily", prefix: "IT9ABC", exact: true, cq: 15, itu: 28, continent: "EU", offset: 1},
+		{call: "VU2ABC", entity: "India", prefix: "VU", cq: 22, itu: 41, continent: "AS", offset: 5.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.call, func(t *testing.T) {
+			m, ok := ds.Resolve(tt.call)
+			if !ok {
+				t.Fatalf("expected a match")
+			}
+			if m.Entity.Name != tt.entity || m.Prefix != tt.prefix || m.Exact != tt.exact {
+				t.Fatalf("unexpected match: entity=%q prefix=%q exact=%v", m.Entity.Name, m.Prefix, m.Exact)
+			}
+			if m.CQZone != tt.cq || m.ITUZone != tt.itu || m.Continent != tt.continent || m.UTCOffset != tt.offset {
+				t.Fatalf("unexpected overrides: %#v", m)
+			}
+		})
+	}
+
+	if _, ok := ds.Resolve("QQ1ABC"); ok {
+		t.Fatalf("expected no match for unknown prefix")
+	}
+}
+
+func TestParseCTY_Invalid(t *testing.T) {
+	inputs := map[string]string{
+		"empty":        "",
+		"unterminated": "Malta: 15: 28: EU: 41.90: -12.43: -1.0: 1A:\n 1A",
+		"bad zone":     "Malta: xx: 28: EU: 41.90: -12.43: -1.0: 1A:\n 1A;",
+		"bad override": "Malta: 15: 28: EU: 41.90: -12.43: -1.0: 1A:\n 1A(15;",
+	}
+	for name, input := range inputs {
+		if _, err := ParseCTY(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
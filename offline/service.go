@@ -0,0 +1,262 @@
+package offline
+
+import (
+	"context"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Station-Manager/config"
+	"github.com/Station-Manager/errors"
+	"github.com/Station-Manager/logging"
+	"github.com/Station-Manager/lookup/internal/calls"
+	"github.com/Station-Manager/lookup/internal/dataset"
+	"github.com/Station-Manager/lookup/internal/upstream"
+	"github.com/Station-Manager/types"
+)
+
+const (
+	// ServiceName identifies the offline cty.dat lookup configuration and provider. The
+	// config's URL holds the path (or file:// URL) of the cty.dat/BigCTY file.
+	ServiceName = "ctylookupservice"
+)
+
+// Service resolves callsigns to DXCC entities from a local cty.dat/BigCTY file, with no
+// network access.
+type Service struct {
+	ConfigService *config.Service  `di.inject:"configservice"`
+	LoggerService *logging.Service `di.inject:"loggingservice"`
+	Config        *types.LookupConfig
+
+	isInitialized atomic.Bool
+	initOnce      sync.Once
+
+	mu      sync.RWMutex
+	dataset *Dataset
+}
+
+// NewService returns an offline lookup service with the provided dependencies. The
+// config.Service is optional if you supply Config directly.
+func NewService(logger *logging.Service, cfgSvc *config.Service, cfg *types.LookupConfig) *Service {
+	return &Service{
+		LoggerService: logger,
+		ConfigService: cfgSvc,
+		Config:        cfg,
+	}
+}
+
+// Initialize initializes the Service instance and loads the configured cty.dat file.
+func (s *Service) Initialize() error {
+	const op errors.Op = "offline.Service.Initialize"
+	if s.isInitialized.Load() {
+		return nil
+	}
+
+	var initErr error
+	s.initOnce.Do(func() {
+		if s.LoggerService == nil {
+			initErr = errors.New(op).Msg("logger service has not been set/injected")
+			return
+		}
+
+		if s.Config == nil {
+			if s.ConfigService == nil {
+				initErr = errors.New(op).Msg("application config has not been set/injected")
+				return
+			}
+
+			cfg, err := s.ConfigService.LookupServiceConfig(ServiceName)
+			if err != nil {
+				initErr = errors.New(op).Err(err).Msg("getting lookup service config")
+				return
+			}
+			s.Config = &cfg
+		}
+
+		if err := s.validateConfig(op); err != nil {
+			initErr = err
+			return
+		}
+
+		if s.Config.Enabled {
+			if err := s.Reload(); err != nil {
+				initErr = err
+				return
+			}
+		} else {
+			s.LoggerService.InfoWith().Msg("Offline cty.dat lookup is disabled in the config")
+		}
+
+		s.isInitialized.Store(true)
+	})
+
+	return initErr
+}
+
+// Reload re-reads the configured cty.dat file, replacing the current dataset only if the
+// new file parses successfully.
+func (s *Service) Reload() error {
+	const op errors.Op = "offline.Service.Reload"
+	if s.Config == nil {
+		return errors.New(op).Msg("service config is not set")
+	}
+
+	path := dataset.Path(s.Config.URL)
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.New(op).Err(err).Msgf("opening cty.dat file %q", path)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	ds, err := ParseCTY(f)
+	if err != nil {
+		return errors.New(op).Err(err).Msgf("parsing cty.dat file %q", path)
+	}
+
+	s.mu.Lock()
+	s.dataset = ds
+	s.mu.Unlock()
+
+	s.LoggerService.InfoWith().Str("path", path).Int("entities", len(ds.Entities)).Msg("Loaded cty.dat dataset")
+	return nil
+}
+
+// SetDataset replaces the dataset used for lookups, e.g. one parsed from an embedded copy.
+func (s *Service) SetDataset(ds *Dataset) {
+	s.mu.Lock()
+	s.dataset = ds
+	s.mu.Unlock()
+}
+
+// Lookup performs a country lookup using a callsign and returns the corresponding country information or an error.
+func (s *Service) Lookup(callsign string) (types.Country, error) {
+	return s.LookupWithContext(context.Background(), callsign)
+}
+
+// LookupWithContext performs a country lookup. The context is accepted for interface
+// compatibility; resolution is in-memory and does not block.
+func (s *Service) LookupWithContext(ctx context.Context, callsign string) (types.Country, error) {
+	const op errors.Op = "offline.Service.LookupWithContext"
+	emptyRetVal := types.Country{}
+
+	if ctx != nil {
+		if err := ctx.Err(); err != nil {
+			return emptyRetVal, errors.New(op).Err(err).Msg("lookup cancelled")
+		}
+	}
+
+	if !s.isInitialized.Load() {
+		return emptyRetVal, errors.New(op).Msg("service is not initialized")
+	}
+	if s.Config == nil {
+		return emptyRetVal, errors.New(op).Msg("service config is not set")
+	}
+
+	if !s.Config.Enabled {
+		if s.LoggerService != nil {
+			s.LoggerService.InfoWith().Msg("Offline cty.dat lookup is disabled in the config")
+		}
+		return types.Country{Name: "Unknown"}, errors.New(op).Err(upstream.ErrDisabled).Msg("Offline cty.dat lookup is disabled in the config")
+	}
+
+	callsign = strings.ToUpper(strings.TrimSpace(callsign))
+	if callsign == "" {
+		return emptyRetVal, errors.New(op).Err(upstream.ErrInvalidCallsign).Msg("callsign cannot be empty")
+	}
+
+	s.mu.RLock()
+	ds := s.dataset
+	s.mu.RUnlock()
+	if ds == nil {
+		return emptyRetVal, errors.New(op).Msg("cty.dat dataset is not loaded")
+	}
+
+	cs, err := calls.Parse(callsign)
+	if err != nil {
+		return emptyRetVal, errors.New(op).Err(err).Msg("invalid callsign")
+	}
+
+	m, ok := resolveCall(ds, cs)
+	if !ok {
+		return emptyRetVal, errors.New(op).Err(errors.ErrNotFound).Msg("Prefix not found in cty.dat")
+	}
+
+	return m.Country(), nil
+}
+
+// resolveCall resolves a parsed callsign. cty.dat may list a slashed call verbatim, so
+// the full form is tried as an exact entry before falling back to the call the station
+// is effectively operating under. Maritime and aeronautical mobile stations resolve to
+// no entity.
+func resolveCall(ds *Dataset, cs calls.Callsign) (*Match, bool) {
+	if m, ok := ds.exact[cs.Raw]; ok {
+		return m, true
+	}
+	if cs.HasNoEntity() {
+		return nil, false
+	}
+	return ds.Resolve(cs.LookupCall())
+}
+
+// Country maps the match into the shared types.Country model.
+func (m *Match) Country() types.Country {
+	country := types.Country{
+		Name:       m.Entity.Name,
+		Prefix:     m.Prefix,
+		Continent:  m.Continent,
+		DXCCPrefix: m.Entity.PrimaryPrefix,
+		TimeOffset: formatUTCOffset(m.UTCOffset),
+	}
+	if m.CQZone != 0 {
+		country.CQZone = strconv.Itoa(m.CQZone)
+	}
+	if m.ITUZone != 0 {
+		country.ITUZone = strconv.Itoa(m.ITUZone)
+	}
+	return country
+}
+
+// formatUTCOffset renders hours east of UTC as "+HH:MM" / "-HH:MM".
+func formatUTCOffset(hours float64) string {
+	sign := "+"
+	if hours < 0 {
+		sign = "-"
+		hours = -hours
+	}
+	total := int(math.Round(hours * 60))
+	h, m := total/60, total%60
+
+	var b strings.Builder
+	b.WriteString(sign)
+	if h < 10 {
+		b.WriteByte('0')
+	}
+	b.WriteString(strconv.Itoa(h))
+	b.WriteByte(':')
+	if m < 10 {
+		b.WriteByte('0')
+	}
+	b.WriteString(strconv.Itoa(m))
+	return b.String()
+}
+
+func (s *Service) validateConfig(op errors.Op) error {
+	if s.Config == nil {
+		return errors.New(op).Msg("service config is not set")
+	}
+
+	if !s.Config.Enabled {
+		return nil
+	}
+
+	if dataset.Path(s.Config.URL) == "" {
+		return errors.New(op).Msg("cty.dat file path (URL) cannot be empty")
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package offline
+
+import (
+	stderrors "errors"
+	"testing"
+
+	smerrors "github.com/Station-Manager/errors"
+	"github.com/Station-Manager/logging"
+	"github.com/Station-Manager/lookup/internal/upstream"
+	"github.com/Station-Manager/types"
+)
+
+func TestService_Initialize_MissingPath(t *testing.T) {
+	s := NewService(&logging.Service{}, nil, &types.LookupConfig{Enabled: true})
+
+	if err := s.Initialize(); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+}
+
+func TestService_Lookup(t *testing.T) {
+	s := NewService(&logging.Service{}, nil, &types.LookupConfig{Enabled: true, URL: "testdata/cty.dat"})
+	if err := s.Initialize(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	country, err := s.Lookup(" vu2abc ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := types.Country{
+		Name:       "India",
+		Prefix:     "VU",
+		Continent:  "AS",
+		CQZone:     "22",
+		ITUZone:    "41",
+		DXCCPrefix: "VU",
+		TimeOffset: "+05:30",
+	}
+	if country != want {
+		t.Fatalf("unexpected country: got %#v want %#v", country, want)
+	}
+
+	_, err = s.Lookup("QQ1ABC")
+	if !stderrors.Is(err, smerrors.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestService_Lookup_Disabled(t *testing.T) {
+	s := NewService(&logging.Service{}, nil, &types.LookupConfig{Enabled: false})
+	if err := s.Initialize(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	country, err := s.Lookup("K1ABC")
+	if !stderrors.Is(err, upstream.ErrDisabled) {
+		t.Fatalf("expected ErrDisabled, got %v", err)
+	}
+	if country.Name != "Unknown" {
+		t.Fatalf("expected Unknown, got %q", country.Name)
+	}
+}
+
+func TestService_Lookup_PortableAndReciprocalForms(t *testing.T) {
+	s := NewService(&logging.Service{}, nil, &types.LookupConfig{Enabled: true, URL: "testdata/cty.dat"})
+	if err := s.Initialize(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		call, name, cqZone string
+	}{
+		{"DL/K1ABC/P", "Fed. Rep. of Germany", "14"},
+		{"K1ABC/KH6", "Hawaii", "31"},
+		{"AH2BW/P", "United States", "27"},
+		{"W1ABC/6", "United States", "3"},
+		{"W1AW/KH6", "Hawaii", "31"},
+		{"SV2ABC/P", "Greece", "20"},
+		{"SV2ABC/A", "Mount Athos", "20"},
+		{"SV2ASP/A", "Mount Athos", "20"},
+	}
+	for _, tt := range tests {
+		country, err := s.Lookup(tt.call)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.call, err)
+		}
+		if country.Name != tt.name || country.CQZone != tt.cqZone {
+			t.Fatalf("%s: unexpected country: %#v", tt.call, country)
+		}
+	}
+
+	if _, err := s.Lookup("K1ABC/MM"); !stderrors.Is(err, smerrors.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for maritime mobile, got %v", err)
+	}
+}
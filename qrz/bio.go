@@ -0,0 +1,128 @@
+package qrz
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"net/url"
+	"strings"
+
+	"github.com/Station-Manager/errors"
+	"github.com/Station-Manager/lookup/internal/upstream"
+)
+
+// Bio is a station's QRZ.com biography, sanitised for display.
+type Bio struct {
+	Callsign string
+	// HTML is the biography with scripts, frames, forms, event handlers and tracking removed.
+	HTML string
+	// Text is a plain-text rendering of HTML.
+	Text string
+}
+
+// FetchBio retrieves and sanitises the biography of the given callsign (html=<call>).
+// Results are cached for the lifetime of the current QRZ.com session.
+func (s *Service) FetchBio(ctx context.Context, callsign string) (Bio, error) {
+	const op errors.Op = "qrz.Service.FetchBio"
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	emptyRetVal := Bio{}
+	if !s.isInitialized.Load() {
+		return emptyRetVal, errors.New(op).Msg("service is not initialized")
+	}
+	if s.Config == nil {
+		return emptyRetVal, errors.New(op).Msg("service config is not set")
+	}
+	if !s.Config.Enabled {
+		return emptyRetVal, errors.New(op).Err(upstream.ErrDisabled).Msg("QRZ.com lookup is disabled in the config")
+	}
+	if s.client == nil {
+		return emptyRetVal, errors.New(op).Msg("http client is not configured")
+	}
+
+	callsign = strings.ToUpper(strings.TrimSpace(callsign))
+	if callsign == "" {
+		return emptyRetVal, errors.New(op).Msg("callsign cannot be empty")
+	}
+
+	if bio, ok := s.cachedBio(callsign); ok {
+		return bio, nil
+	}
+
+	var bio Bio
+	var sessionKey string
+	err := s.withSession(ctx, func(key string) error {
+		body, err := s.fetch(ctx, key, url.Values{"html": {callsign}})
+		if err != nil {
+			return err
+		}
+		sessionKey = key
+		bio, err = s.unmarshalBio(callsign, body)
+		return err
+	})
+	if err != nil {
+		return emptyRetVal, errors.New(op).Errorf("QRZ.com biography fetch failed: %w", err)
+	}
+
+	s.storeBio(sessionKey, bio)
+
+	return bio, nil
+}
+
+// unmarshalBio interprets the response to an html= request. QRZ.com answers with the raw
+// biography HTML on success and with the usual XML document when something went wrong.
+func (s *Service) unmarshalBio(callsign string, body []byte) (Bio, error) {
+	const op errors.Op = "qrz.Service.unmarshalBio"
+
+	trimmed := bytes.TrimSpace(body)
+	if bytes.HasPrefix(trimmed, []byte("<?xml")) || bytes.Contains(trimmed, []byte("<QRZDatabase")) {
+		var db Database
+		if err := xml.Unmarshal(trimmed, &db); err != nil {
+			return Bio{}, errors.New(op).Err(upstream.Tag(upstream.ErrMalformedResponse, err)).Msg("failed to unmarshal QRZ XML response")
+		}
+		s.recordSession(db.Session)
+		if err := sessionError(op, db.Session); err != nil {
+			return Bio{}, err
+		}
+		return Bio{}, errors.New(op).Err(errors.ErrNotFound).Msg("biography not present in QRZ response")
+	}
+
+	if len(trimmed) == 0 {
+		return Bio{}, errors.New(op).Err(errors.ErrNotFound).Msg("biography is empty")
+	}
+
+	sanitized, text, err := sanitizeHTML(string(trimmed))
+	if err != nil {
+		return Bio{}, errors.New(op).Err(err).Msg("failed to sanitise biography HTML")
+	}
+
+	return Bio{Callsign: callsign, HTML: sanitized, Text: text}, nil
+}
+
+// cachedBio returns a biography fetched under the current session, if any.
+func (s *Service) cachedBio(callsign string) (Bio, bool) {
+	s.sessionMu.Lock()
+	defer s.sessionMu.Unlock()
+	if s.bioCacheKey != s.session.Token() {
+		return Bio{}, false
+	}
+	bio, ok := s.bioCache[callsign]
+	return bio, ok
+}
+
+// storeBio caches a biography fetched with sessionKey. The cache is discarded whenever
+// the session is renewed so stale biographies do not outlive the session.
+func (s *Service) storeBio(sessionKey string, bio Bio) {
+	s.sessionMu.Lock()
+	defer s.sessionMu.Unlock()
+	if sessionKey != s.session.Token() {
+		return
+	}
+	if s.bioCache == nil || s.bioCacheKey != sessionKey {
+		s.bioCache = make(map[string]Bio)
+		s.bioCacheKey = sessionKey
+	}
+	s.bioCache[bio.Callsign] = bio
+}
@@ -0,0 +1,88 @@
+package qrz
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSanitizeHTML(t *testing.T) {
+	raw := `<html><head><title>x</title><script>alert(1)</script></head><body>
+<h1 onclick="steal()">Hello from AA7BQ</h1>
+<p style="color:red">Visit <a href="https://example.com/?utm_source=qrz&amp;id=5" onmouseover="x()">my site</a>
+or <a href="javascript:alert(1)">this</a>.</p>
+<iframe src="https://evil.example.com"></iframe>
+<img src="https://tracker.example.com/p.gif" width="1" height="1">
+<img src="https://example.com/shack.jpg" alt="Shack">
+<font color="blue">73 de Fred</font>
+<!-- hidden -->
+</body></html>`
+
+	sanitized, text, err := sanitizeHTML(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, banned := range []string{"<script", "alert", "<iframe", "onclick", "onmouseover", "style=", "tracker.example.com", "utm_source", "<font", "hidden", "<title"} {
+		if strings.Contains(sanitized, banned) {
+			t.Errorf("sanitised HTML still contains %q:\n%s", banned, sanitized)
+		}
+	}
+	for _, kept := range []string{"<h1>Hello from AA7BQ</h1>", `href="https://example.com/?id=5"`, `rel="nofollow noopener noreferrer"`, `src="https://example.com/shack.jpg"`, "73 de Fred"} {
+		if !strings.Contains(sanitized, kept) {
+			t.Errorf("sanitised HTML is missing %q:\n%s", kept, sanitized)
+		}
+	}
+
+	wantText := "Hello from AA7BQ\nVisit my site or this.\n73 de Fred"
+	if text != wantText {
+		t.Fatalf("unexpected text:\n%q\nwant\n%q", text, wantText)
+	}
+}
+
+func TestService_FetchBio_CachedPerSession(t *testing.T) {
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Query().Get("html") != "AA7BQ" {
+			_, _ = w.Write([]byte(`<?xml version="1.0"?><QRZDatabase version="1.34"><Session><Key>k</Key><Error>Not found: XX0XX</Error></Session></QRZDatabase>`))
+			return
+		}
+		_, _ = w.Write([]byte(`<p>Licensed in <b>1975</b></p><script>track()</script>`))
+	}))
+	defer ts.Close()
+
+	s := newTestService(ts)
+	s.session.Set("k")
+
+	bio, err := s.FetchBio(context.Background(), "aa7bq")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bio.HTML != "<p>Licensed in <b>1975</b></p>" || bio.Text != "Licensed in 1975" {
+		t.Fatalf("unexpected bio: %#v", bio)
+	}
+
+	if _, err = s.FetchBio(context.Background(), "AA7BQ"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Fatalf("expected cached biography, got %d requests", got)
+	}
+
+	// A new session invalidates the cache.
+	s.session.Set("k2")
+	if _, err = s.FetchBio(context.Background(), "AA7BQ"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Fatalf("expected a fresh fetch after session change, got %d requests", got)
+	}
+
+	if _, err = s.FetchBio(context.Background(), "XX0XX"); err == nil {
+		t.Fatalf("expected error for unknown callsign, got nil")
+	}
+}
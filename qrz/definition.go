@@ -2,6 +2,9 @@ package qrz
 
 import (
 	"encoding/xml"
+	"time"
+
+	"github.com/Station-Manager/types"
 )
 
 type Callsign struct {
@@ -60,9 +63,53 @@ type Session struct {
 	Error  string `xml:"Error"`
 }
 
+// DXCC models a single DXCC element returned by a dxcc=<callsign|entity|all> query.
+type DXCC struct {
+	Dxcc      string `xml:"dxcc"`
+	Cc        string `xml:"cc"`
+	Ccc       string `xml:"ccc"`
+	Name      string `xml:"name"`
+	Continent string `xml:"continent"`
+	Ituzone   string `xml:"ituzone"`
+	Cqzone    string `xml:"cqzone"`
+	Timezone  string `xml:"timezone"`
+	Lat       string `xml:"lat"`
+	Lon       string `xml:"lon"`
+	Notes     string `xml:"notes"`
+}
+
 type Database struct {
 	XMLName  xml.Name `xml:"QRZDatabase"`
 	Version  string   `xml:"version,attr"`
 	Callsign Callsign `xml:"Callsign"`
+	DXCC     []DXCC   `xml:"DXCC"`
 	Session  Session  `xml:"Session"`
 }
+
+// SessionInfo summarises the subscription and quota state reported in the Session
+// element of the most recent QRZ.com response.
+type SessionInfo struct {
+	// LookupsToday is the number of lookups performed by this account in the current 24-hour period.
+	LookupsToday int
+	// SubscriptionExpires is when the account's XML subscription ends. It is the zero
+	// time for non-subscribers or when QRZ.com did not report a parseable date.
+	SubscriptionExpires time.Time
+	// NonSubscriber is true when the account has no XML subscription and QRZ.com only
+	// returns a reduced set of fields.
+	NonSubscriber bool
+	// ServerTime is the QRZ.com server time at which the response was generated.
+	ServerTime time.Time
+	// Remark carries any informational message from QRZ.com.
+	Remark string
+	// UpdatedAt is the local time at which this information was received.
+	UpdatedAt time.Time
+}
+
+// Result is a station lookup together with the state of the session that served it.
+type Result struct {
+	Station types.ContactedStation
+	// Limited is true when QRZ.com withheld fields because the account is not a subscriber.
+	Limited bool
+	// Session is the state reported by the response that served this lookup.
+	Session SessionInfo
+}
@@ -0,0 +1,259 @@
+package qrz
+
+import (
+	"context"
+	stderr "errors"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Station-Manager/errors"
+	"github.com/Station-Manager/lookup/breaker"
+	"github.com/Station-Manager/lookup/internal/calls"
+	"github.com/Station-Manager/lookup/internal/upstream"
+	"github.com/Station-Manager/lookup/metrics"
+	"github.com/Station-Manager/lookup/observer"
+	"github.com/Station-Manager/types"
+)
+
+const (
+	// DXCCServiceName identifies the QRZ.com DXCC provider in lookup.ServiceFactory. It shares
+	// the QRZ.com lookup configuration (ServiceName) as it uses the same account and session.
+	DXCCServiceName = "qrzdxcclookupservice"
+)
+
+// DXCCEntity is a DXCC record as reported by the QRZ.com XML interface.
+type DXCCEntity struct {
+	Number      int
+	CountryCode string // ISO 3166 alpha-2
+	CountryISO3 string // ISO 3166 alpha-3
+	Name        string
+	Continent   string
+	CQZone      string
+	ITUZone     string
+	UTCOffset   string // "+HH:MM" / "-HH:MM"
+	Lat         float64
+	Lon         float64
+	Notes       string
+	// Prefix is the prefix the entity was resolved from. It is only set for callsign
+	// queries, where it holds the effective prefix of the queried callsign (e.g. "KH6"
+	// for W1AW/KH6).
+	Prefix string
+}
+
+// Country maps the entity into the shared types.Country model. DXCCPrefix is left empty:
+// QRZ.com does not report an entity's primary prefix.
+func (e DXCCEntity) Country() types.Country {
+	return types.Country{
+		Name:       e.Name,
+		Prefix:     e.Prefix,
+		Ccode:      e.CountryCode,
+		Continent:  e.Continent,
+		CQZone:     e.CQZone,
+		ITUZone:    e.ITUZone,
+		TimeOffset: e.UTCOffset,
+	}
+}
+
+// DXCCService exposes QRZ.com's dxcc= query as a lookup.Provider. It shares the session
+// of the underlying Service, so callsign and DXCC lookups count against the same login.
+type DXCCService struct {
+	svc *Service
+}
+
+// NewDXCCService returns a DXCC provider backed by the given QRZ.com service.
+func NewDXCCService(svc *Service) *DXCCService {
+	return &DXCCService{svc: svc}
+}
+
+// Service returns the QRZ.com service the DXCC provider is backed by, so its settings
+// (Transport, RateLimit and so on) can be adjusted before Initialize.
+func (d *DXCCService) Service() *Service {
+	return d.svc
+}
+
+// Initialize initializes the underlying QRZ.com service.
+func (d *DXCCService) Initialize() error {
+	const op errors.Op = "qrz.DXCCService.Initialize"
+	if d.svc == nil {
+		return errors.New(op).Msg("QRZ.com service has not been set")
+	}
+	return d.svc.Initialize()
+}
+
+// Lookup resolves a callsign to its DXCC entity using the default context.
+func (d *DXCCService) Lookup(callsign string) (types.Country, error) {
+	return d.LookupWithContext(context.Background(), callsign)
+}
+
+// LookupWithContext resolves a callsign to its DXCC entity using the supplied context so
+// callers can enforce cancellation and deadlines.
+func (d *DXCCService) LookupWithContext(ctx context.Context, callsign string) (types.Country, error) {
+	const op errors.Op = "qrz.DXCCService.LookupWithContext"
+	if d.svc == nil {
+		return types.Country{}, errors.New(op).Msg("QRZ.com service has not been set")
+	}
+	ctx, observed := observer.Start(ctx, d.svc.Observer, DXCCServiceName, callsign)
+	done := metrics.Start(d.svc.Metrics, DXCCServiceName)
+	country, err := d.lookup(ctx, callsign)
+	done(err)
+	observed(err)
+	return country, err
+}
+
+func (d *DXCCService) lookup(ctx context.Context, callsign string) (types.Country, error) {
+	const op errors.Op = "qrz.DXCCService.LookupWithContext"
+
+	if d.svc.Config != nil && !d.svc.Config.Enabled {
+		d.svc.LoggerService.InfoWith().Msg("QRZ.com DXCC lookup is disabled in the config")
+		return types.Country{Name: "Unknown"}, errors.New(op).Err(upstream.ErrDisabled).Msg("QRZ.com DXCC lookup is disabled in the config")
+	}
+
+	callsign = strings.TrimSpace(callsign)
+	if callsign == "" {
+		return types.Country{}, errors.New(op).Err(upstream.ErrInvalidCallsign).Msg("callsign cannot be empty")
+	}
+
+	cs, err := calls.Validate(callsign)
+	if err != nil {
+		return types.Country{}, errors.New(op).Err(err).Msg("invalid callsign")
+	}
+	if cs.HasNoEntity() {
+		return types.Country{}, errors.New(op).Err(errors.ErrNotFound).Msgf("%s is %s and has no DXCC entity", cs.Raw, cs.Indicator)
+	}
+
+	if err = d.svc.limiter.Admit(ctx); err != nil {
+		return types.Country{}, errors.New(op).Err(err).Msg("QRZ.com is held back by the rate limiter")
+	}
+
+	// Concurrent lookups of the same call share one request, as callsign lookups do.
+	query := cs.LookupCall()
+	entities, _, err := d.svc.dxccInflight.Do(ctx, query, func(ctx context.Context) ([]DXCCEntity, error) {
+		return d.svc.DXCC(ctx, query)
+	})
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil && stderr.Is(err, ctxErr) {
+			return types.Country{}, errors.New(op).Err(err).Msg("QRZ.com DXCC lookup cancelled")
+		}
+		return types.Country{}, err
+	}
+
+	entity := entities[0]
+	entity.Prefix = cs.EffectivePrefix()
+	return entity.Country(), nil
+}
+
+// BreakerState returns the state of the underlying QRZ.com service's circuit breaker.
+func (d *DXCCService) BreakerState() breaker.State {
+	if d.svc == nil {
+		return breaker.Disabled
+	}
+	return d.svc.BreakerState()
+}
+
+// Entities returns the full list of DXCC entities known to QRZ.com (dxcc=all).
+func (d *DXCCService) Entities(ctx context.Context) ([]DXCCEntity, error) {
+	const op errors.Op = "qrz.DXCCService.Entities"
+	if d.svc == nil {
+		return nil, errors.New(op).Msg("QRZ.com service has not been set")
+	}
+	return d.svc.DXCC(ctx, "all")
+}
+
+// DXCC performs a dxcc= query, where query is a callsign, a DXCC entity number or "all".
+// Not-found conditions are reported as errors.ErrNotFound.
+func (s *Service) DXCC(ctx context.Context, query string) ([]DXCCEntity, error) {
+	const op errors.Op = "qrz.Service.DXCC"
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if !s.isInitialized.Load() {
+		return nil, errors.New(op).Msg("service is not initialized")
+	}
+	if s.Config == nil {
+		return nil, errors.New(op).Msg("service config is not set")
+	}
+	if !s.Config.Enabled {
+		return nil, errors.New(op).Err(upstream.ErrDisabled).Msg("QRZ.com lookup is disabled in the config")
+	}
+	if s.client == nil {
+		return nil, errors.New(op).Msg("http client is not configured")
+	}
+
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, errors.New(op).Msg("DXCC query cannot be empty")
+	}
+
+	var entities []DXCCEntity
+	err := s.withSession(ctx, func(key string) error {
+		body, err := s.fetch(ctx, key, url.Values{"dxcc": {query}})
+		if err != nil {
+			return err
+		}
+		entities, err = s.unmarshalDXCC(body)
+		return err
+	})
+	if err != nil {
+		return nil, errors.New(op).Errorf("QRZ.com DXCC lookup failed: %w", err)
+	}
+
+	return entities, nil
+}
+
+// newDXCCEntity converts a raw DXCC element into a DXCCEntity.
+func newDXCCEntity(d DXCC) DXCCEntity {
+	trim := strings.TrimSpace
+	entity := DXCCEntity{
+		CountryCode: strings.ToUpper(trim(d.Cc)),
+		CountryISO3: strings.ToUpper(trim(d.Ccc)),
+		Name:        trim(d.Name),
+		Continent:   strings.ToUpper(trim(d.Continent)),
+		CQZone:      trim(d.Cqzone),
+		ITUZone:     trim(d.Ituzone),
+		UTCOffset:   formatUTCOffset(d.Timezone),
+		Notes:       trim(d.Notes),
+	}
+	entity.Number, _ = strconv.Atoi(trim(d.Dxcc))
+	entity.Lat, _ = strconv.ParseFloat(trim(d.Lat), 64)
+	entity.Lon, _ = strconv.ParseFloat(trim(d.Lon), 64)
+	return entity
+}
+
+// formatUTCOffset converts QRZ.com's timezone value (hours, possibly fractional, e.g.
+// "-5" or "5.5") into the "+HH:MM" form used by types.Country. Values that already
+// contain a colon are passed through; unparseable values yield an empty string.
+func formatUTCOffset(tz string) string {
+	tz = strings.TrimSpace(tz)
+	if tz == "" || strings.Contains(tz, ":") {
+		return tz
+	}
+
+	hours, err := strconv.ParseFloat(tz, 64)
+	if err != nil {
+		return ""
+	}
+
+	sign := "+"
+	if hours < 0 {
+		sign = "-"
+		hours = -hours
+	}
+	totalMinutes := int(math.Round(hours * 60))
+	h, m := totalMinutes/60, totalMinutes%60
+
+	var b strings.Builder
+	b.WriteString(sign)
+	if h < 10 {
+		b.WriteByte('0')
+	}
+	b.WriteString(strconv.Itoa(h))
+	b.WriteByte(':')
+	if m < 10 {
+		b.WriteByte('0')
+	}
+	b.WriteString(strconv.Itoa(m))
+	return b.String()
+}
@@ -0,0 +1,162 @@
+package qrz
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	smerrors "github.com/Station-Manager/errors"
+)
+
+const dxccXML = `<?xml version="1.0"?>
+<QRZDatabase version="1.34">
+  <DXCC>
+    <dxcc>291</dxcc>
+    <cc>US</cc>
+    <ccc>USA</ccc>
+    <name>United States</name>
+    <continent>NA</continent>
+    <ituzone>6</ituzone>
+    <cqzone>3</cqzone>
+    <timezone>-5</timezone>
+    <lat>37.701207</lat>
+    <lon>-97.316895</lon>
+    <notes>Nothing special</notes>
+  </DXCC>
+  <Session><Key>k</Key></Session>
+</QRZDatabase>`
+
+const dxccAllXML = `<?xml version="1.0"?>
+<QRZDatabase version="1.34">
+  <DXCC><dxcc>1</dxcc><name>Canada</name><continent>NA</continent></DXCC>
+  <DXCC><dxcc>291</dxcc><name>United States</name><continent>NA</continent></DXCC>
+  <DXCC><dxcc>339</dxcc><name>Japan</name><continent>AS</continent><timezone>9</timezone></DXCC>
+  <Session><Key>k</Key></Session>
+</QRZDatabase>`
+
+func newDXCCTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("dxcc") {
+		case "all":
+			_, _ = w.Write([]byte(dxccAllXML))
+		case "AA7BQ", "291":
+			_, _ = w.Write([]byte(dxccXML))
+		default:
+			_, _ = w.Write([]byte(`<QRZDatabase version="1.34"><Session><Key>k</Key><Error>DXCC not found</Error></Session></QRZDatabase>`))
+		}
+	}))
+}
+
+func TestDXCCService_LookupWithContext(t *testing.T) {
+	ts := newDXCCTestServer(t)
+	defer ts.Close()
+
+	svc := newTestService(ts)
+	svc.session.Set("k")
+	d := NewDXCCService(svc)
+
+	country, err := d.LookupWithContext(context.Background(), " AA7BQ ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if country.Name != "United States" || country.Ccode != "US" || country.Continent != "NA" {
+		t.Fatalf("unexpected country: %#v", country)
+	}
+	if country.CQZone != "3" || country.ITUZone != "6" {
+		t.Fatalf("unexpected zones: %#v", country)
+	}
+	if country.TimeOffset != "-05:00" {
+		t.Fatalf("unexpected TimeOffset: %q", country.TimeOffset)
+	}
+	if country.Prefix != "AA7" || country.DXCCPrefix != "" {
+		t.Fatalf("unexpected prefixes: Prefix=%q DXCCPrefix=%q", country.Prefix, country.DXCCPrefix)
+	}
+}
+
+func TestDXCCService_LookupWithContext_CoalescesConcurrentLookups(t *testing.T) {
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte(dxccXML))
+	}))
+	defer ts.Close()
+
+	svc := newTestService(ts)
+	svc.session.Set("k")
+	d := NewDXCCService(svc)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := d.Lookup("AA7BQ"); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := requests.Load(); got != 1 {
+		t.Fatalf("expected one shared request, got %d", got)
+	}
+}
+
+func TestDXCCService_LookupWithContext_NotFound(t *testing.T) {
+	ts := newDXCCTestServer(t)
+	defer ts.Close()
+
+	svc := newTestService(ts)
+	svc.session.Set("k")
+	d := NewDXCCService(svc)
+
+	_, err := d.Lookup("XX0XX")
+	if !stderrors.Is(err, smerrors.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestDXCCService_Entities(t *testing.T) {
+	ts := newDXCCTestServer(t)
+	defer ts.Close()
+
+	svc := newTestService(ts)
+	svc.session.Set("k")
+	d := NewDXCCService(svc)
+
+	entities, err := d.Entities(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entities) != 3 {
+		t.Fatalf("expected 3 entities, got %d", len(entities))
+	}
+	if entities[2].Number != 339 || entities[2].UTCOffset != "+09:00" {
+		t.Fatalf("unexpected entity: %#v", entities[2])
+	}
+}
+
+func TestFormatUTCOffset(t *testing.T) {
+	tests := map[string]string{
+		"":      "",
+		"-5":    "-05:00",
+		"5.5":   "+05:30",
+		"5.75":  "+05:45",
+		"-3.5":  "-03:30",
+		"12":    "+12:00",
+		"+5:45": "+5:45",
+		"bogus": "",
+	}
+	for in, want := range tests {
+		if got := formatUTCOffset(in); got != want {
+			t.Errorf("formatUTCOffset(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
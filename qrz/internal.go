@@ -1,89 +1,205 @@
 package qrz
 
 import (
+	"context"
 	"encoding/xml"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/Station-Manager/errors"
+	"github.com/Station-Manager/lookup/breaker"
+	"github.com/Station-Manager/lookup/internal/guard"
+	"github.com/Station-Manager/lookup/internal/session"
+	"github.com/Station-Manager/lookup/internal/upstream"
+	"github.com/Station-Manager/lookup/observer"
 	"github.com/Station-Manager/types"
 )
 
-// fetchAndSetSessionKey fetches a session key from the configured QRZ endpoint and assigns it to the service instance.
-// It validates initialization, builds the request, handles errors, and processes the XML response for the session key.
-// Errors returned:
-func (s *Service) requestAndSetSessionKey() error {
-	const op errors.Op = "qrz.Service.fetchAndSetSessionKey"
+// errSessionExpired marks QRZ.com session errors that can be resolved by logging in again.
+var errSessionExpired = session.ErrExpired
+
+// requestAndSetSessionKey logs in to QRZ.com with the configured credentials and assigns
+// the returned session key to the service instance. The login goes through fetch, so it
+// is rate limited, retried and guarded by the circuit breaker like any other request.
+func (s *Service) requestAndSetSessionKey(ctx context.Context) error {
+	const op errors.Op = "qrz.Service.requestAndSetSessionKey"
+
+	body, err := s.fetch(ctx, "", url.Values{
+		"username": {s.Config.Username},
+		"password": {s.Config.Password},
+	})
+	if err != nil {
+		return err
+	}
+
+	var db Database
+	if err = xml.Unmarshal(body, &db); err != nil {
+		err = errors.New(op).Err(upstream.Tag(upstream.ErrMalformedResponse, err)).Msg("Failed to unmarshal XML")
+		return err
+	}
+
+	s.recordSession(db.Session)
+
+	// Check for API-level error; at login that means the credentials were refused.
+	if db.Session.Error != "" {
+		err = errors.New(op).Err(upstream.ErrUnauthorized).Msgf("QRZ.com returned error: %s", db.Session.Error)
+		return err
+	}
+	if db.Session.Key == "" {
+		err = errors.New(op).Err(upstream.ErrMalformedResponse).Msg("QRZ.com returned missing session key")
+		return err
+	}
+
+	s.session.Set(db.Session.Key)
+
+	return nil
+}
+
+// withSession runs fn with the current session key. If fn fails because the session
+// has expired, the session is renewed once and fn is retried with the new key.
+func (s *Service) withSession(ctx context.Context, fn func(key string) error) error {
+	return s.session.Do(ctx, fn, func(ctx context.Context) error {
+		s.LoggerService.InfoWith().Msg("QRZ.com session expired, logging in again")
+		return s.requestAndSetSessionKey(ctx)
+	})
+}
+
+// fetch performs a GET against the QRZ.com XML interface, retrying transient failures
+// as configured by Retry. While the circuit breaker is open it fails straight away.
+func (s *Service) fetch(ctx context.Context, key string, params url.Values) ([]byte, error) {
+	return guard.Do(ctx, s.upstream(), func(ctx context.Context, attempt int) ([]byte, error) {
+		return s.fetchOnce(ctx, key, params, attempt)
+	})
+}
+
+// BreakerState returns the state of the circuit breaker in front of QRZ.com, or
+// breaker.Disabled if the provider is disabled in its config.
+func (s *Service) BreakerState() breaker.State {
+	if s.Config != nil && !s.Config.Enabled {
+		return breaker.Disabled
+	}
+	return s.circuit.State()
+}
+
+// upstream describes QRZ.com to the guard that retries its requests.
+func (s *Service) upstream() guard.Upstream {
+	return guard.Upstream{Name: "QRZ.com", Breaker: s.circuit, Retry: s.Retry, Logger: s.LoggerService}
+}
+
+// fetchOnce performs a single GET against the QRZ.com XML interface using the given
+// session key and additional query parameters, returning the raw response body. An empty
+// key sends no session, as for the login itself. attempt numbers the request for the
+// Observer.
+func (s *Service) fetchOnce(ctx context.Context, key string, params url.Values, attempt int) ([]byte, error) {
+	const op errors.Op = "qrz.Service.fetchOnce"
 
 	u, err := url.Parse(s.Config.URL)
 	if err != nil {
-		return errors.New(op).Err(err).Msg("invalid QRZ base URL")
+		return nil, errors.New(op).Err(err).Msg("invalid QRZ base URL")
 	}
 
 	q := u.Query()
-	q.Set("username", s.Config.Username)
-	q.Set("password", s.Config.Password)
+	for k, v := range params {
+		q[k] = v
+	}
+	if key != "" {
+		q.Set("s", key)
+	}
 	q.Set("agent", s.Config.UserAgent)
 	u.RawQuery = q.Encode()
 
-	// Build request to set headers (User-Agent is often required)
-	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err = s.limiter.Wait(ctx); err != nil {
+		return nil, errors.New(op).Err(err).Msg("Request held back by the rate limiter")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
-		err = errors.New(op).Errorf("Failed to create HTTP GET request: %w", err)
-		return err
+		return nil, errors.New(op).Err(err).Msg("Failed to create HTTP GET request")
 	}
+
 	req.Header.Set("User-Agent", s.Config.UserAgent)
 	req.Header.Set("Accept", "application/xml")
 
+	callsign := params.Get("callsign")
+	if callsign == "" {
+		callsign = params.Get("dxcc")
+	}
+	observed := observer.Request(s.Observer, ServiceName, callsign, attempt, req, "username", "password")
 	resp, err := s.client.Do(req)
+	observed(resp, err)
 	if err != nil {
-		err = errors.New(op).Errorf("Failed to perform HTTP GET request: %w", err)
-		return err
+		return nil, errors.New(op).Err(upstream.Unreachable(ctx, err)).Msg("Failed to perform HTTP GET request")
 	}
 	defer func(Body io.ReadCloser) {
-		if e := Body.Close(); e != nil {
-			s.LoggerService.ErrorWith().Err(err).Msg("Failed to close response body")
-		}
+		_ = Body.Close()
 	}(resp.Body)
 
+	if rle := upstream.RateLimitFromResponse(resp); rle != nil {
+		s.limiter.Pause(rle.RetryAfter)
+		return nil, errors.New(op).Err(rle).Msgf("QRZ.com rate limit exceeded (HTTP %d)", resp.StatusCode)
+	}
+
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		// Try to read body for context
 		b, _ := io.ReadAll(resp.Body)
-		err = errors.New(op).Errorf("QRZ.com returned unexpected status %d: %s", resp.StatusCode, string(b))
-		return err
+		return nil, errors.New(op).Err(&upstream.StatusError{StatusCode: resp.StatusCode, Body: string(b)}).Msgf("Service returned unexpected status %d: %s", resp.StatusCode, string(b))
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		err = errors.New(op).Errorf("Failed to read response body: %w", err)
-		return err
+		return nil, errors.New(op).Err(upstream.Unreachable(ctx, err)).Msg("Failed to read response body")
 	}
 
-	var db Database
-	if err = xml.Unmarshal(body, &db); err != nil {
-		err = errors.New(op).Errorf("Failed to unmarshal XML: %w", err)
-		return err
+	return body, nil
+}
+
+// recordSession stores the subscription and quota details from a response's Session
+// element as the service's latest and returns them, so the lookup that received them
+// can report its own rather than whatever a concurrent lookup stored since.
+func (s *Service) recordSession(sess Session) SessionInfo {
+	info := newSessionInfo(sess)
+	s.sessionMu.Lock()
+	s.sessionInfo = info
+	s.sessionMu.Unlock()
+	return info
+}
+
+// newSessionInfo converts a raw Session element into a SessionInfo.
+func newSessionInfo(sess Session) SessionInfo {
+	info := SessionInfo{
+		LookupsToday: sess.Count,
+		Remark:       strings.TrimSpace(sess.Remark),
+		UpdatedAt:    time.Now(),
 	}
 
-	// Check for API-level error
-	if db.Session.Error != "" {
-		err = errors.New(op).Errorf("QRZ.com returned error: %s", db.Session.Error)
-		return err
+	subExp := strings.TrimSpace(sess.SubExp)
+	if strings.EqualFold(subExp, "non-subscriber") {
+		info.NonSubscriber = true
+	} else if t, err := time.Parse(time.ANSIC, subExp); err == nil {
+		info.SubscriptionExpires = t
 	}
-	if db.Session.Key == "" {
-		err = errors.New(op).Msg("QRZ.com returned missing session key")
-		return err
+
+	if t, err := time.Parse(time.ANSIC, strings.TrimSpace(sess.GMTime)); err == nil {
+		info.ServerTime = t
 	}
 
-	// Set the sesion key
-	s.sessionKey = db.Session.Key
+	return info
+}
 
-	return nil
+// isSessionError reports whether a QRZ.com session error message indicates that the
+// session key is no longer valid and a fresh login is required.
+func isSessionError(msg string) bool {
+	lower := strings.ToLower(msg)
+	return strings.Contains(lower, "session timeout") ||
+		strings.Contains(lower, "invalid session key") ||
+		strings.Contains(lower, "session does not exist")
 }
 
-func (s *Service) unmarshalResponse(body []byte) (types.ContactedStation, error) {
+// unmarshalResponse decodes the response to a callsign= query into the station and the
+// session details it carried.
+func (s *Service) unmarshalResponse(body []byte) (types.ContactedStation, SessionInfo, error) {
 	const op errors.Op = "qrz.Service.unmarshalResponse"
 
 	var (
@@ -92,17 +208,13 @@ func (s *Service) unmarshalResponse(body []byte) (types.ContactedStation, error)
 	)
 
 	if err := xml.Unmarshal(body, &db); err != nil {
-		return station, errors.New(op).Err(err).Msg("failed to unmarshal QRZ XML response")
+		return station, SessionInfo{}, errors.New(op).Err(upstream.Tag(upstream.ErrMalformedResponse, err)).Msg("failed to unmarshal QRZ XML response")
 	}
 
-	sessionErr := strings.TrimSpace(db.Session.Error)
-	if sessionErr != "" {
-		lower := strings.ToLower(sessionErr)
-		errBuilder := errors.New(op).Msg(sessionErr)
-		if strings.Contains(lower, "not found") {
-			errBuilder = errBuilder.Err(errors.ErrNotFound)
-		}
-		return station, errBuilder
+	info := s.recordSession(db.Session)
+
+	if err := sessionError(op, db.Session); err != nil {
+		return station, info, err
 	}
 
 	cs := db.Callsign
@@ -137,7 +249,7 @@ func (s *Service) unmarshalResponse(body []byte) (types.ContactedStation, error)
 
 	call := strings.ToUpper(trim(cs.Call))
 	if call == "" {
-		return station, errors.New(op).Err(errors.ErrNotFound).Msg("callsign not present in QRZ response")
+		return station, info, errors.New(op).Err(errors.ErrNotFound).Msg("callsign not present in QRZ response")
 	}
 
 	station.Call = call
@@ -156,7 +268,55 @@ func (s *Service) unmarshalResponse(body []byte) (types.ContactedStation, error)
 	station.Lon = trim(cs.Lon)
 	station.ContactedOp = trim(cs.Attn)
 
-	return station, nil
+	return station, info, nil
+}
+
+// unmarshalDXCC decodes the response to a dxcc= query into DXCC entities.
+func (s *Service) unmarshalDXCC(body []byte) ([]DXCCEntity, error) {
+	const op errors.Op = "qrz.Service.unmarshalDXCC"
+
+	var db Database
+	if err := xml.Unmarshal(body, &db); err != nil {
+		return nil, errors.New(op).Err(upstream.Tag(upstream.ErrMalformedResponse, err)).Msg("failed to unmarshal QRZ XML response")
+	}
+
+	s.recordSession(db.Session)
+
+	if err := sessionError(op, db.Session); err != nil {
+		return nil, err
+	}
+
+	entities := make([]DXCCEntity, 0, len(db.DXCC))
+	for _, d := range db.DXCC {
+		entity := newDXCCEntity(d)
+		if entity.Number == 0 {
+			continue
+		}
+		entities = append(entities, entity)
+	}
+
+	if len(entities) == 0 {
+		return nil, errors.New(op).Err(errors.ErrNotFound).Msg("DXCC entity not present in QRZ response")
+	}
+
+	return entities, nil
+}
+
+// sessionError converts an error reported in a Session element into an error value,
+// tagging not-found and expired-session conditions so callers can branch on them.
+func sessionError(op errors.Op, sess Session) error {
+	sessionErr := strings.TrimSpace(sess.Error)
+	if sessionErr == "" {
+		return nil
+	}
+
+	errBuilder := errors.New(op).Msg(sessionErr)
+	if strings.Contains(strings.ToLower(sessionErr), "not found") {
+		errBuilder = errBuilder.Err(errors.ErrNotFound)
+	} else if isSessionError(sessionErr) {
+		errBuilder = errBuilder.Err(errSessionExpired)
+	}
+	return errBuilder
 }
 
 func (s *Service) validateConfig(op errors.Op) error {
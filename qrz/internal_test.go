@@ -5,6 +5,7 @@ import (
 	smerrors "github.com/Station-Manager/errors"
 	"github.com/Station-Manager/types"
 	"testing"
+	"time"
 )
 
 func TestService_unmarshalResponse(t *testing.T) {
@@ -109,7 +110,7 @@ func TestService_unmarshalResponse(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := service.unmarshalResponse([]byte(tt.payload))
+			got, _, err := service.unmarshalResponse([]byte(tt.payload))
 
 			if tt.wantErr {
 				if err == nil {
@@ -134,3 +135,36 @@ func TestService_unmarshalResponse(t *testing.T) {
 		})
 	}
 }
+
+func TestNewSessionInfo(t *testing.T) {
+	info := newSessionInfo(Session{
+		Count:  42,
+		SubExp: "Wed Jan  1 12:34:03 2031",
+		GMTime: "Sun Nov 22 21:25:34 2026",
+		Remark: " cpu: 0.022s ",
+	})
+
+	if info.LookupsToday != 42 {
+		t.Fatalf("unexpected LookupsToday: %d", info.LookupsToday)
+	}
+	if info.NonSubscriber {
+		t.Fatalf("expected subscriber")
+	}
+	if want := time.Date(2031, time.January, 1, 12, 34, 3, 0, time.UTC); !info.SubscriptionExpires.Equal(want) {
+		t.Fatalf("unexpected SubscriptionExpires: %v", info.SubscriptionExpires)
+	}
+	if want := time.Date(2026, time.November, 22, 21, 25, 34, 0, time.UTC); !info.ServerTime.Equal(want) {
+		t.Fatalf("unexpected ServerTime: %v", info.ServerTime)
+	}
+	if info.Remark != "cpu: 0.022s" {
+		t.Fatalf("unexpected Remark: %q", info.Remark)
+	}
+
+	info = newSessionInfo(Session{SubExp: "non-subscriber"})
+	if !info.NonSubscriber {
+		t.Fatalf("expected non-subscriber")
+	}
+	if !info.SubscriptionExpires.IsZero() {
+		t.Fatalf("expected zero SubscriptionExpires, got %v", info.SubscriptionExpires)
+	}
+}
@@ -0,0 +1,72 @@
+package logbook
+
+// Actions supported by the QRZ.com Logbook API.
+const (
+	actionStatus = "STATUS"
+	actionFetch  = "FETCH"
+	actionInsert = "INSERT"
+	actionDelete = "DELETE"
+)
+
+// Values of the RESULT field returned by the QRZ.com Logbook API.
+const (
+	resultOK      = "OK"
+	resultReplace = "REPLACE"
+	resultPartial = "PARTIAL"
+	resultFail    = "FAIL"
+	resultAuth    = "AUTH"
+)
+
+// Response is the decoded key=value payload of a QRZ.com Logbook API reply. Keys are
+// upper-cased; the ADIF payload of FETCH replies is HTML-unescaped.
+type Response map[string]string
+
+// Status describes the logbook associated with the API key.
+type Status struct {
+	BookName  string
+	Owner     string
+	Callsign  string
+	QSOCount  int
+	Confirmed int
+	DXCCTotal int
+	StartDate string
+	EndDate   string
+	// Data holds every key reported by QRZ.com, including those not mapped above.
+	Data map[string]string
+}
+
+// FetchOptions narrows a FETCH request. Zero values are omitted from the OPTION field.
+type FetchOptions struct {
+	// Confirmed restricts the result to confirmed QSOs.
+	Confirmed bool
+	// Max limits the number of QSOs returned; QRZ.com caps this server side.
+	Max int
+	// AfterLogID returns only QSOs with a logid greater than this value, for paging.
+	AfterLogID int64
+	// Call restricts the result to QSOs with the given station.
+	Call string
+	// ModSince returns QSOs modified on or after this date (YYYY-MM-DD).
+	ModSince string
+}
+
+// FetchResult is the outcome of a FETCH request.
+type FetchResult struct {
+	Count  int
+	LogIDs []string
+	// ADIF contains the fetched QSOs as ADIF records.
+	ADIF string
+}
+
+// InsertResult is the outcome of an INSERT request.
+type InsertResult struct {
+	LogID string
+	// Replaced is true when an existing QSO was overwritten (OPTION=REPLACE).
+	Replaced bool
+}
+
+// DeleteResult is the outcome of a DELETE request.
+type DeleteResult struct {
+	Count int
+	// NotDeleted lists the logids QRZ.com could not delete when only some succeeded.
+	NotDeleted []string
+}
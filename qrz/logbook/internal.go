@@ -0,0 +1,183 @@
+package logbook
+
+import (
+	"context"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Station-Manager/errors"
+	"github.com/Station-Manager/lookup/internal/upstream"
+)
+
+// post sends a form-encoded request for the given action to the Logbook API and decodes
+// the key=value reply. FAIL and AUTH results are reported as errors.
+func (s *Service) post(ctx context.Context, action string, form url.Values) (Response, error) {
+	const op errors.Op = "logbook.Service.post"
+
+	if form == nil {
+		form = url.Values{}
+	}
+	form.Set("KEY", s.Config.APIKey)
+	form.Set("ACTION", action)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.Config.URL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, errors.New(op).Err(err).Msg("Failed to create HTTP POST request")
+	}
+	req.Header.Set("User-Agent", s.Config.UserAgent)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, errors.New(op).Err(upstream.Unreachable(ctx, err)).Msg("Failed to perform HTTP POST request")
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return nil, errors.New(op).Err(&upstream.StatusError{StatusCode: resp.StatusCode, Body: string(b)}).Msgf("QRZ.com Logbook returned unexpected status %d: %s", resp.StatusCode, string(b))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.New(op).Err(upstream.Unreachable(ctx, err)).Msg("Failed to read response body")
+	}
+
+	r := parseResponse(string(body))
+	switch r["RESULT"] {
+	case resultOK, resultReplace, resultPartial:
+		return r, nil
+	case resultAuth:
+		return r, errors.New(op).Err(ErrUnauthorized).Msgf("QRZ.com Logbook rejected the API key: %s", reasonOf(r))
+	case resultFail:
+		return r, errors.New(op).Msgf("QRZ.com Logbook %s failed: %s", action, reasonOf(r))
+	default:
+		return r, errors.New(op).Msgf("QRZ.com Logbook returned an unrecognised response: %q", string(body))
+	}
+}
+
+// parseResponse decodes a Logbook API reply. Replies are '&'-separated key=value pairs,
+// except that ADIF (always last) is HTML-escaped rather than URL-encoded and so may
+// itself contain '&'; it is therefore split off before the remaining pairs are decoded.
+func parseResponse(body string) Response {
+	r := Response{}
+	body = strings.TrimSpace(body)
+
+	if idx := adifIndex(body); idx >= 0 {
+		r["ADIF"] = html.UnescapeString(body[idx+len("ADIF="):])
+		body = strings.TrimSuffix(body[:idx], "&")
+	}
+
+	for _, pair := range strings.Split(body, "&") {
+		if pair == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(pair, "=")
+		if v, err := url.QueryUnescape(value); err == nil {
+			value = v
+		}
+		r[strings.ToUpper(strings.TrimSpace(key))] = value
+	}
+
+	return r
+}
+
+// adifIndex returns the offset of the ADIF key in body, or -1.
+func adifIndex(body string) int {
+	if strings.HasPrefix(strings.ToUpper(body), "ADIF=") {
+		return 0
+	}
+	if idx := strings.Index(strings.ToUpper(body), "&ADIF="); idx >= 0 {
+		return idx + 1
+	}
+	return -1
+}
+
+// reasonOf returns the REASON field of a reply, or a placeholder when absent.
+func reasonOf(r Response) string {
+	if reason := strings.TrimSpace(r["REASON"]); reason != "" {
+		return reason
+	}
+	return "no reason given"
+}
+
+// splitList splits a comma-separated list, dropping empty entries.
+func splitList(v string) []string {
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// atoi parses an integer field, treating anything unparseable as zero.
+func atoi(v string) int {
+	n, _ := strconv.Atoi(strings.TrimSpace(v))
+	return n
+}
+
+// option renders FetchOptions into the OPTION field of a FETCH request.
+func (o FetchOptions) option() string {
+	parts := []string{"TYPE:ADIF"}
+	if o.Confirmed {
+		parts = append(parts, "STATUS:CONFIRMED")
+	}
+	if o.Max > 0 {
+		parts = append(parts, "MAX:"+strconv.Itoa(o.Max))
+	}
+	if o.AfterLogID > 0 {
+		parts = append(parts, "AFTERLOGID:"+strconv.FormatInt(o.AfterLogID, 10))
+	}
+	if call := strings.TrimSpace(o.Call); call != "" {
+		parts = append(parts, "CALL:"+strings.ToUpper(call))
+	}
+	if since := strings.TrimSpace(o.ModSince); since != "" {
+		parts = append(parts, "MODSINCE:"+since)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (s *Service) validateConfig(op errors.Op) error {
+	if s.Config == nil {
+		return errors.New(op).Msg("service config is not set")
+	}
+
+	// Don't check the config it the service is not enabled
+	if !s.Config.Enabled {
+		return nil
+	}
+
+	s.Config.URL = strings.TrimSpace(s.Config.URL)
+	if s.Config.URL == "" {
+		s.Config.URL = DefaultURL
+	}
+
+	u, err := url.Parse(s.Config.URL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return errors.New(op).Err(err).Msg("logbook service URL is invalid")
+	}
+
+	s.Config.UserAgent = strings.TrimSpace(s.Config.UserAgent)
+	if s.Config.UserAgent == "" {
+		return errors.New(op).Msg("logbook service user agent cannot be empty")
+	}
+
+	if s.Config.HttpTimeoutSec <= 0 {
+		return errors.New(op).Msg("logbook service timeout must be greater than zero")
+	}
+
+	s.Config.APIKey = strings.TrimSpace(s.Config.APIKey)
+	if s.Config.APIKey == "" {
+		return errors.New(op).Msg("QRZ.com Logbook API key cannot be empty")
+	}
+
+	return nil
+}
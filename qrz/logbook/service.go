@@ -0,0 +1,241 @@
+package logbook
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Station-Manager/config"
+	"github.com/Station-Manager/errors"
+	"github.com/Station-Manager/logging"
+	"github.com/Station-Manager/lookup/internal/upstream"
+	"github.com/Station-Manager/lookup/transport"
+	"github.com/Station-Manager/types"
+)
+
+const (
+	ServiceName = types.QrzForwardingServiceName
+
+	// DefaultURL is the QRZ.com Logbook API endpoint used when the config leaves URL empty.
+	DefaultURL = "https://logbook.qrz.com/api"
+)
+
+// ErrUnauthorized is returned when QRZ.com rejects the API key (RESULT=AUTH). It is the
+// shared lookup.ErrUnauthorized.
+var ErrUnauthorized = upstream.ErrUnauthorized
+
+// Service is a client for the QRZ.com Logbook API. Unlike the XML callbook it is
+// authenticated with a per-logbook API key rather than a session.
+type Service struct {
+	ConfigService *config.Service  `di.inject:"configservice"`
+	LoggerService *logging.Service `di.inject:"loggingservice"`
+	Config        *types.ForwarderConfig
+	client        *http.Client
+
+	// Transport configures the HTTP client; see package transport. It is ignored when a
+	// client is passed to NewService.
+	Transport transport.Config
+
+	isInitialized atomic.Bool
+	initOnce      sync.Once
+}
+
+// NewService returns a QRZ.com Logbook client with the provided dependencies. The
+// config.Service is optional if you supply Config directly. The client can be
+// overridden for testing; otherwise it will be created during Initialize.
+func NewService(logger *logging.Service, cfgSvc *config.Service, cfg *types.ForwarderConfig, client *http.Client) *Service {
+	return &Service{
+		LoggerService: logger,
+		ConfigService: cfgSvc,
+		Config:        cfg,
+		client:        client,
+	}
+}
+
+// Initialize initializes the Service instance by setting up required dependencies and configurations.
+func (s *Service) Initialize() error {
+	const op errors.Op = "logbook.Service.Initialize"
+	if s.isInitialized.Load() {
+		return nil
+	}
+
+	var initErr error
+	s.initOnce.Do(func() {
+		if s.LoggerService == nil {
+			initErr = errors.New(op).Msg("logger service has not been set/injected")
+			return
+		}
+
+		if s.Config == nil {
+			if s.ConfigService == nil {
+				initErr = errors.New(op).Msg("application config has not been set/injected")
+				return
+			}
+
+			cfg, err := s.ConfigService.ForwarderConfig(ServiceName)
+			if err != nil {
+				initErr = errors.New(op).Err(err).Msg("getting logbook service config")
+				return
+			}
+			s.Config = &cfg
+		}
+
+		if err := s.validateConfig(op); err != nil {
+			initErr = err
+			return
+		}
+
+		if s.client == nil {
+			if s.Config.Enabled {
+				client, err := s.Transport.NewClient(s.Config.HttpTimeoutSec * time.Second)
+				if err != nil {
+					initErr = errors.New(op).Err(err).Msg("invalid HTTP transport settings")
+					return
+				}
+				s.client = client
+			} else {
+				s.LoggerService.InfoWith().Msg("QRZ.com Logbook is disabled in the config")
+			}
+		}
+
+		s.isInitialized.Store(true)
+	})
+
+	return initErr
+}
+
+// Status returns the details of the logbook associated with the API key.
+func (s *Service) Status(ctx context.Context) (Status, error) {
+	const op errors.Op = "logbook.Service.Status"
+	if err := s.checkReady(op); err != nil {
+		return Status{}, err
+	}
+
+	r, err := s.post(ctx, actionStatus, nil)
+	if err != nil {
+		return Status{}, errors.New(op).Errorf("QRZ.com Logbook status failed: %w", err)
+	}
+
+	data := map[string]string{}
+	for _, pair := range strings.Split(r["DATA"], "&") {
+		if key, value, ok := strings.Cut(pair, "="); ok {
+			if v, uErr := url.QueryUnescape(value); uErr == nil {
+				value = v
+			}
+			data[strings.ToUpper(key)] = value
+		}
+	}
+	// Some replies carry the status fields at the top level rather than inside DATA.
+	for key, value := range r {
+		if _, ok := data[key]; !ok && key != "RESULT" && key != "DATA" {
+			data[key] = value
+		}
+	}
+
+	return Status{
+		BookName:  data["BOOK_NAME"],
+		Owner:     data["OWNER"],
+		Callsign:  data["CALLSIGN"],
+		QSOCount:  atoi(data["COUNT"]),
+		Confirmed: atoi(data["CONFIRMED"]),
+		DXCCTotal: atoi(data["DXCC_COUNT"]),
+		StartDate: data["START_DATE"],
+		EndDate:   data["END_DATE"],
+		Data:      data,
+	}, nil
+}
+
+// Fetch retrieves QSOs from the logbook as ADIF.
+func (s *Service) Fetch(ctx context.Context, opts FetchOptions) (FetchResult, error) {
+	const op errors.Op = "logbook.Service.Fetch"
+	if err := s.checkReady(op); err != nil {
+		return FetchResult{}, err
+	}
+
+	r, err := s.post(ctx, actionFetch, url.Values{"OPTION": {opts.option()}})
+	if err != nil {
+		return FetchResult{}, errors.New(op).Errorf("QRZ.com Logbook fetch failed: %w", err)
+	}
+
+	return FetchResult{
+		Count:  atoi(r["COUNT"]),
+		LogIDs: splitList(r["LOGIDS"]),
+		ADIF:   r["ADIF"],
+	}, nil
+}
+
+// Insert uploads a single QSO, given as an ADIF record. When replace is true an existing
+// duplicate QSO is overwritten instead of the upload being rejected.
+func (s *Service) Insert(ctx context.Context, adif string, replace bool) (InsertResult, error) {
+	const op errors.Op = "logbook.Service.Insert"
+	if err := s.checkReady(op); err != nil {
+		return InsertResult{}, err
+	}
+
+	adif = strings.TrimSpace(adif)
+	if adif == "" {
+		return InsertResult{}, errors.New(op).Msg("ADIF record cannot be empty")
+	}
+
+	form := url.Values{"ADIF": {adif}}
+	if replace {
+		form.Set("OPTION", "REPLACE")
+	}
+
+	r, err := s.post(ctx, actionInsert, form)
+	if err != nil {
+		return InsertResult{}, errors.New(op).Errorf("QRZ.com Logbook insert failed: %w", err)
+	}
+
+	return InsertResult{LogID: r["LOGID"], Replaced: r["RESULT"] == resultReplace}, nil
+}
+
+// Delete removes the QSOs with the given logids. A partial success is not an error;
+// the logids that could not be deleted are reported in the result.
+func (s *Service) Delete(ctx context.Context, logIDs ...int64) (DeleteResult, error) {
+	const op errors.Op = "logbook.Service.Delete"
+	if err := s.checkReady(op); err != nil {
+		return DeleteResult{}, err
+	}
+
+	if len(logIDs) == 0 {
+		return DeleteResult{}, errors.New(op).Msg("at least one logid is required")
+	}
+	ids := make([]string, len(logIDs))
+	for i, id := range logIDs {
+		ids[i] = strconv.FormatInt(id, 10)
+	}
+
+	r, err := s.post(ctx, actionDelete, url.Values{"LOGIDS": {strings.Join(ids, ",")}})
+	if err != nil {
+		return DeleteResult{}, errors.New(op).Errorf("QRZ.com Logbook delete failed: %w", err)
+	}
+
+	result := DeleteResult{Count: atoi(r["COUNT"])}
+	if r["RESULT"] == resultPartial {
+		result.NotDeleted = splitList(r["LOGIDS"])
+	}
+	return result, nil
+}
+
+// checkReady verifies the service can issue requests.
+func (s *Service) checkReady(op errors.Op) error {
+	if !s.isInitialized.Load() {
+		return errors.New(op).Msg("service is not initialized")
+	}
+	if s.Config == nil {
+		return errors.New(op).Msg("service config is not set")
+	}
+	if !s.Config.Enabled {
+		return errors.New(op).Err(upstream.ErrDisabled).Msg("QRZ.com Logbook is disabled in the config")
+	}
+	if s.client == nil {
+		return errors.New(op).Msg("http client is not configured")
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package logbook
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/Station-Manager/logging"
+	"github.com/Station-Manager/lookup"
+	"github.com/Station-Manager/types"
+)
+
+// newTestServer returns a Logbook API stand-in that answers each ACTION with a canned reply.
+func newTestServer(t *testing.T, replies map[string]string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("expected POST, got %s", r.Method)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("parsing form: %v", err)
+		}
+		if r.PostForm.Get("KEY") == "bad" {
+			_, _ = w.Write([]byte("RESULT=AUTH&REASON=invalid api key"))
+			return
+		}
+		_, _ = w.Write([]byte(replies[r.PostForm.Get("ACTION")]))
+	}))
+}
+
+func newTestService(t *testing.T, ts *httptest.Server, apiKey string) *Service {
+	t.Helper()
+	cfg := &types.ForwarderConfig{Enabled: true, URL: ts.URL, APIKey: apiKey, UserAgent: "test", HttpTimeoutSec: 5}
+	s := NewService(&logging.Service{}, nil, cfg, ts.Client())
+	if err := s.Initialize(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return s
+}
+
+func TestService_Initialize_MissingAPIKey(t *testing.T) {
+	cfg := &types.ForwarderConfig{Enabled: true, UserAgent: "test", HttpTimeoutSec: 5}
+	s := NewService(&logging.Service{}, nil, cfg, nil)
+
+	if err := s.Initialize(); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+}
+
+func TestService_Initialize_DefaultURL(t *testing.T) {
+	cfg := &types.ForwarderConfig{Enabled: true, APIKey: "k", UserAgent: "test", HttpTimeoutSec: 5}
+	s := NewService(&logging.Service{}, nil, cfg, nil)
+
+	if err := s.Initialize(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Config.URL != DefaultURL {
+		t.Fatalf("expected default URL, got %q", s.Config.URL)
+	}
+}
+
+func TestService_Status(t *testing.T) {
+	ts := newTestServer(t, map[string]string{
+		actionStatus: "RESULT=OK&DATA=BOOK_NAME%3DMain%26OWNER%3DAA7BQ%26COUNT%3D1234%26CONFIRMED%3D321%26DXCC_COUNT%3D150",
+	})
+	defer ts.Close()
+
+	status, err := newTestService(t, ts, "k").Status(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status.BookName != "Main" || status.Owner != "AA7BQ" || status.QSOCount != 1234 || status.Confirmed != 321 || status.DXCCTotal != 150 {
+		t.Fatalf("unexpected status: %#v", status)
+	}
+}
+
+func TestService_Fetch(t *testing.T) {
+	ts := newTestServer(t, map[string]string{
+		actionFetch: "RESULT=OK&COUNT=2&LOGIDS=101,102&ADIF=&lt;call:5&gt;AA7BQ&lt;eor&gt;&lt;call:4&gt;K1AB&lt;eor&gt;",
+	})
+	defer ts.Close()
+
+	result, err := newTestService(t, ts, "k").Fetch(context.Background(), FetchOptions{Confirmed: true, Max: 10})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Count != 2 || !reflect.DeepEqual(result.LogIDs, []string{"101", "102"}) {
+		t.Fatalf("unexpected result: %#v", result)
+	}
+	if result.ADIF != "<call:5>AA7BQ<eor><call:4>K1AB<eor>" {
+		t.Fatalf("unexpected ADIF: %q", result.ADIF)
+	}
+}
+
+func TestService_InsertAndDelete(t *testing.T) {
+	ts := newTestServer(t, map[string]string{
+		actionInsert: "RESULT=REPLACE&LOGID=555&COUNT=1",
+		actionDelete: "RESULT=PARTIAL&COUNT=1&LOGIDS=7",
+	})
+	defer ts.Close()
+
+	s := newTestService(t, ts, "k")
+
+	inserted, err := s.Insert(context.Background(), "<call:5>AA7BQ<eor>", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if inserted.LogID != "555" || !inserted.Replaced {
+		t.Fatalf("unexpected insert result: %#v", inserted)
+	}
+
+	deleted, err := s.Delete(context.Background(), 6, 7)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if deleted.Count != 1 || !reflect.DeepEqual(deleted.NotDeleted, []string{"7"}) {
+		t.Fatalf("unexpected delete result: %#v", deleted)
+	}
+}
+
+func TestService_Failures(t *testing.T) {
+	ts := newTestServer(t, map[string]string{
+		actionInsert: "RESULT=FAIL&REASON=Unable to add QSO to database: duplicate&COUNT=0",
+	})
+	defer ts.Close()
+
+	if _, err := newTestService(t, ts, "k").Insert(context.Background(), "<call:5>AA7BQ<eor>", false); err == nil {
+		t.Fatalf("expected error for FAIL result, got nil")
+	}
+
+	_, err := newTestService(t, ts, "bad").Status(context.Background())
+	if !stderrors.Is(err, lookup.ErrUnauthorized) {
+		t.Fatalf("expected lookup.ErrUnauthorized, got %v", err)
+	}
+}
+
+func TestService_DisabledAndUnreachable(t *testing.T) {
+	s := NewService(&logging.Service{}, nil, &types.ForwarderConfig{Enabled: false}, nil)
+	if err := s.Initialize(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := s.Status(context.Background()); !stderrors.Is(err, lookup.ErrDisabled) {
+		t.Fatalf("expected lookup.ErrDisabled, got %v", err)
+	}
+
+	ts := newTestServer(t, nil)
+	s = newTestService(t, ts, "k")
+	ts.Close()
+	if _, err := s.Status(context.Background()); !stderrors.Is(err, lookup.ErrUnavailable) {
+		t.Fatalf("expected lookup.ErrUnavailable, got %v", err)
+	}
+}
+
+func TestParseResponse(t *testing.T) {
+	r := parseResponse("RESULT=OK&REASON=all%20good&ADIF=&lt;a:1&gt;x &amp; y")
+	if r["RESULT"] != "OK" || r["REASON"] != "all good" || r["ADIF"] != "<a:1>x & y" {
+		t.Fatalf("unexpected response: %#v", r)
+	}
+}
@@ -0,0 +1,262 @@
+package qrz
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// droppedElements are removed together with everything they contain.
+var droppedElements = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Iframe:   true,
+	atom.Frame:    true,
+	atom.Frameset: true,
+	atom.Object:   true,
+	atom.Embed:    true,
+	atom.Applet:   true,
+	atom.Form:     true,
+	atom.Input:    true,
+	atom.Button:   true,
+	atom.Select:   true,
+	atom.Textarea: true,
+	atom.Link:     true,
+	atom.Meta:     true,
+	atom.Base:     true,
+	atom.Noscript: true,
+	atom.Svg:      true,
+	atom.Math:     true,
+	atom.Audio:    true,
+	atom.Video:    true,
+	atom.Source:   true,
+	atom.Template: true,
+	atom.Title:    true,
+	atom.Head:     true,
+}
+
+// allowedElements lists the elements kept in sanitised output together with the
+// attributes each may carry. Elements that are neither allowed nor dropped are
+// unwrapped: the element goes but its children stay.
+var allowedElements = map[atom.Atom]map[string]bool{
+	atom.A:          {"href": true, "title": true},
+	atom.Img:        {"src": true, "alt": true, "title": true, "width": true, "height": true},
+	atom.P:          {},
+	atom.Br:         {},
+	atom.Hr:         {},
+	atom.B:          {},
+	atom.Strong:     {},
+	atom.I:          {},
+	atom.Em:         {},
+	atom.U:          {},
+	atom.S:          {},
+	atom.Small:      {},
+	atom.Sub:        {},
+	atom.Sup:        {},
+	atom.Blockquote: {},
+	atom.Pre:        {},
+	atom.Code:       {},
+	atom.H1:         {},
+	atom.H2:         {},
+	atom.H3:         {},
+	atom.H4:         {},
+	atom.H5:         {},
+	atom.H6:         {},
+	atom.Ul:         {},
+	atom.Ol:         {},
+	atom.Li:         {},
+	atom.Table:      {},
+	atom.Thead:      {},
+	atom.Tbody:      {},
+	atom.Tr:         {},
+	atom.Th:         {"colspan": true, "rowspan": true},
+	atom.Td:         {"colspan": true, "rowspan": true},
+	atom.Div:        {},
+	atom.Span:       {},
+	atom.Center:     {},
+}
+
+// blockElements start a new line in the plain-text rendering.
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Br: true, atom.Hr: true, atom.Li: true, atom.Tr: true,
+	atom.Blockquote: true, atom.Pre: true, atom.Table: true, atom.Ul: true, atom.Ol: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Center: true,
+}
+
+// sanitizeHTML parses an untrusted HTML fragment and returns a sanitised HTML rendering
+// together with a plain-text version. Active content (scripts, frames, forms, event
+// handlers, javascript: URLs) and tracking (1x1 pixels, utm_* parameters) are removed.
+func sanitizeHTML(raw string) (string, string, error) {
+	container := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	nodes, err := html.ParseFragment(strings.NewReader(raw), container)
+	if err != nil {
+		return "", "", err
+	}
+
+	root := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	for _, n := range nodes {
+		root.AppendChild(n)
+	}
+	sanitizeChildren(root)
+
+	var buf bytes.Buffer
+	for c := root.FirstChild; c != nil; c = c.NextSibling {
+		if err = html.Render(&buf, c); err != nil {
+			return "", "", err
+		}
+	}
+
+	var text strings.Builder
+	renderText(&text, root)
+
+	return strings.TrimSpace(buf.String()), normalizeText(text.String()), nil
+}
+
+// sanitizeChildren sanitises the children of n in place.
+func sanitizeChildren(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		switch c.Type {
+		case html.TextNode:
+		case html.ElementNode:
+			sanitizeElement(n, c)
+		default:
+			// Comments, doctypes and the like carry nothing worth showing.
+			n.RemoveChild(c)
+		}
+		c = next
+	}
+}
+
+// sanitizeElement sanitises the element c, a child of parent, removing, unwrapping or
+// cleaning it as required.
+func sanitizeElement(parent, c *html.Node) {
+	if droppedElements[c.DataAtom] {
+		parent.RemoveChild(c)
+		return
+	}
+
+	sanitizeChildren(c)
+
+	allowedAttrs, ok := allowedElements[c.DataAtom]
+	if !ok {
+		// Unwrap: hoist the (already sanitised) children into the parent.
+		for gc := c.FirstChild; gc != nil; {
+			next := gc.NextSibling
+			c.RemoveChild(gc)
+			parent.InsertBefore(gc, c)
+			gc = next
+		}
+		parent.RemoveChild(c)
+		return
+	}
+
+	attrs := c.Attr[:0]
+	for _, a := range c.Attr {
+		key := strings.ToLower(a.Key)
+		if a.Namespace != "" || !allowedAttrs[key] {
+			continue
+		}
+		if key == "href" || key == "src" {
+			cleaned, ok := sanitizeURL(a.Val)
+			if !ok {
+				continue
+			}
+			a.Val = cleaned
+		}
+		a.Key = key
+		attrs = append(attrs, a)
+	}
+	c.Attr = attrs
+
+	switch c.DataAtom {
+	case atom.Img:
+		if attrValue(c, "src") == "" || isTrackingPixel(c) {
+			parent.RemoveChild(c)
+		}
+	case atom.A:
+		if attrValue(c, "href") != "" {
+			c.Attr = append(c.Attr,
+				html.Attribute{Key: "rel", Val: "nofollow noopener noreferrer"},
+				html.Attribute{Key: "target", Val: "_blank"},
+			)
+		}
+	}
+}
+
+// sanitizeURL accepts only http(s) and mailto URLs and strips tracking query parameters.
+func sanitizeURL(raw string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+	case "mailto":
+		return u.String(), true
+	default:
+		return "", false
+	}
+
+	q := u.Query()
+	for k := range q {
+		lower := strings.ToLower(k)
+		if strings.HasPrefix(lower, "utm_") || lower == "fbclid" || lower == "gclid" || lower == "mc_eid" {
+			q.Del(k)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), true
+}
+
+// isTrackingPixel reports whether an image is a (near) invisible beacon.
+func isTrackingPixel(n *html.Node) bool {
+	small := func(v string) bool {
+		v = strings.TrimSuffix(strings.TrimSpace(v), "px")
+		return v == "0" || v == "1"
+	}
+	return small(attrValue(n, "width")) || small(attrValue(n, "height"))
+}
+
+func attrValue(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// renderText writes the text content of n, breaking lines at block elements.
+func renderText(b *strings.Builder, n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch c.Type {
+		case html.TextNode:
+			// Source line breaks are insignificant in HTML; only block elements break lines.
+			b.WriteString(strings.ReplaceAll(c.Data, "\n", " "))
+		case html.ElementNode:
+			if blockElements[c.DataAtom] {
+				b.WriteByte('\n')
+			}
+			renderText(b, c)
+			if blockElements[c.DataAtom] {
+				b.WriteByte('\n')
+			}
+		}
+	}
+}
+
+// normalizeText collapses runs of whitespace within lines and drops blank lines.
+func normalizeText(s string) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
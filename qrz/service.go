@@ -2,7 +2,6 @@ package qrz
 
 import (
 	"context"
-	"io"
 	"net/http"
 	"net/url"
 	"strings"
@@ -15,24 +14,86 @@ import (
 	"github.com/Station-Manager/config"
 	"github.com/Station-Manager/errors"
 	"github.com/Station-Manager/logging"
+	"github.com/Station-Manager/lookup/breaker"
+	"github.com/Station-Manager/lookup/internal/calls"
+	"github.com/Station-Manager/lookup/internal/coalesce"
+	"github.com/Station-Manager/lookup/internal/session"
+	"github.com/Station-Manager/lookup/internal/upstream"
+	"github.com/Station-Manager/lookup/metrics"
+	"github.com/Station-Manager/lookup/observer"
+	"github.com/Station-Manager/lookup/ratelimit"
+	"github.com/Station-Manager/lookup/retry"
+	"github.com/Station-Manager/lookup/transport"
 	"github.com/Station-Manager/types"
-	"github.com/Station-Manager/utils"
 )
 
 const (
 	ServiceName = types.QrzLookupServiceName
 )
 
+// DefaultRateLimit is applied when Service.RateLimit is left at its zero value. QRZ.com
+// meters XML lookups per account and day (SessionInfo.LookupsToday) rather than per
+// second; the bucket keeps a bulk import from bursting through one account's quota.
+var DefaultRateLimit = ratelimit.Config{Rate: 2, Burst: 5}
+
 type Service struct {
 	ConfigService *config.Service  `di.inject:"configservice"`
 	LoggerService *logging.Service `di.inject:"loggingservice"`
 	Config        *types.LookupConfig
 	client        *http.Client
 
+	// Transport configures the HTTP client; see package transport. It is ignored when a
+	// client is passed to NewService.
+	Transport transport.Config
+
+	// RateLimit throttles requests, logins and DXCCService lookups included; the zero
+	// value selects DefaultRateLimit.
+	RateLimit ratelimit.Config
+	limiter   *ratelimit.Limiter
+
+	// Retry governs retries of transient failures; see package retry.
+	Retry retry.Policy
+
+	// Breaker configures the circuit breaker, which DXCCService shares; see package
+	// breaker.
+	Breaker breaker.Config
+	circuit *breaker.Breaker
+
+	// Metrics, if set, records every lookup under the provider label ServiceName, and
+	// DXCCService lookups under DXCCServiceName.
+	Metrics metrics.Recorder
+
+	// Observer, if set, is told about every lookup and every request sent to QRZ.com,
+	// logins included. DXCCService lookups are reported under DXCCServiceName.
+	Observer observer.Observer
+
+	// inflight coalesces concurrent lookups of the same callsign into one request.
+	inflight coalesce.Group[string, Result]
+	// dxccInflight does the same for DXCCService lookups.
+	dxccInflight coalesce.Group[string, []DXCCEntity]
+
 	isInitialized atomic.Bool
 	initOnce      sync.Once
 
-	sessionKey string
+	session session.Session
+
+	// sessionMu guards the session details and the biography cache below.
+	sessionMu   sync.Mutex
+	sessionInfo SessionInfo
+	bioCache    map[string]Bio
+	bioCacheKey string
+}
+
+// NewService returns a QRZ.com lookup service with the provided dependencies. The
+// config.Service is optional if you supply Config directly. The client can be
+// overridden for testing; otherwise it will be created during Initialize.
+func NewService(logger *logging.Service, cfgSvc *config.Service, cfg *types.LookupConfig, client *http.Client) *Service {
+	return &Service{
+		LoggerService: logger,
+		ConfigService: cfgSvc,
+		Config:        cfg,
+		client:        client,
+	}
 }
 
 // Initialize initializes the Service instance by setting up required dependencies and configurations.
@@ -68,17 +129,27 @@ func (s *Service) Initialize() error {
 			return
 		}
 
-		if s.client == nil {
-			if s.Config.Enabled {
-				s.client = utils.NewHTTPClient(s.Config.HttpTimeoutSec * time.Second)
-				if err := s.requestAndSetSessionKey(); err != nil {
-					// Any error here and we should disable the service
-					s.Config.Enabled = false
-					initErr = err
+		if s.RateLimit == (ratelimit.Config{}) {
+			s.RateLimit = DefaultRateLimit
+		}
+		s.limiter = ratelimit.New(s.RateLimit)
+		s.circuit = breaker.New(s.Breaker)
+
+		if !s.Config.Enabled {
+			s.LoggerService.InfoWith().Msg("QRZ.com callsign lookup is disabled in the config")
+		} else {
+			if s.client == nil {
+				client, err := s.Transport.NewClient(s.Config.HttpTimeoutSec * time.Second)
+				if err != nil {
+					initErr = errors.New(op).Err(err).Msg("invalid HTTP transport settings")
 					return
 				}
-			} else {
-				s.LoggerService.InfoWith().Msg("QRZ.com callsign lookup is disabled in the config")
+				s.client = client
+			}
+			if err := s.requestAndSetSessionKey(context.Background()); err != nil {
+				// The service stays uninitialized; Config is left as supplied.
+				initErr = err
+				return
 			}
 		}
 
@@ -91,11 +162,6 @@ func (s *Service) Initialize() error {
 // Lookup retrieves information about a contacted station by its callsign.
 // It uses the default context and returns the station details or an error.
 func (s *Service) Lookup(callsign string) (types.ContactedStation, error) {
-	if !s.Config.Enabled {
-		s.LoggerService.InfoWith().Msg("QRZ.com lookup not enabled in the config.")
-		// If not enabled, just return an empty station object - NO ERROR
-		return types.ContactedStation{}, nil
-	}
 	return s.LookupWithContext(context.Background(), callsign)
 }
 
@@ -103,12 +169,29 @@ func (s *Service) Lookup(callsign string) (types.ContactedStation, error) {
 // It validates the service's initialization state, builds the request, and processes the response or returns an error.
 // Returns a ContactedStation object with details or an error if the retrieval fails.
 func (s *Service) LookupWithContext(ctx context.Context, callsign string) (types.ContactedStation, error) {
-	const op errors.Op = "qrz.service.LookupWithContext"
+	result, err := s.LookupDetailed(ctx, callsign)
+	return result.Station, err
+}
+
+// LookupDetailed behaves like LookupWithContext but also reports the session state that
+// served the request and whether the result was reduced because the account is not a
+// QRZ.com subscriber.
+func (s *Service) LookupDetailed(ctx context.Context, callsign string) (Result, error) {
+	ctx, observed := observer.Start(ctx, s.Observer, ServiceName, callsign)
+	done := metrics.Start(s.Metrics, ServiceName)
+	result, err := s.lookupDetailed(ctx, callsign)
+	done(err)
+	observed(err)
+	return result, err
+}
+
+func (s *Service) lookupDetailed(ctx context.Context, callsign string) (Result, error) {
+	const op errors.Op = "qrz.Service.LookupDetailed"
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
-	emptyRetVal := types.ContactedStation{}
+	emptyRetVal := Result{}
 	if !s.isInitialized.Load() {
 		return emptyRetVal, errors.New(op).Msg("service is not initialized")
 	}
@@ -121,59 +204,55 @@ func (s *Service) LookupWithContext(ctx context.Context, callsign string) (types
 	// This check is here because if the client is disabled, the HTTP client will not be initialized
 	if !s.Config.Enabled {
 		s.LoggerService.InfoWith().Msg("QRZ.com callsign lookup is disabled in the config")
-		return types.ContactedStation{Call: callsign}, nil
+		return Result{Station: types.ContactedStation{Call: callsign}}, errors.New(op).Err(upstream.ErrDisabled).Msg("QRZ.com callsign lookup is disabled in the config")
 	}
 
 	if s.client == nil {
 		return emptyRetVal, errors.New(op).Msg("http client is not configured")
 	}
 
-	u, err := url.Parse(s.Config.URL)
-	if err != nil {
-		return emptyRetVal, errors.New(op).Err(err).Msg("invalid QRZ base URL")
-	}
-
-	q := u.Query()
-	q.Set("s", s.sessionKey)
-	q.Set("callsign", callsign)
-	q.Set("agent", s.Config.UserAgent)
-	u.RawQuery = q.Encode()
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	// Invalid input is rejected before it costs a request. Callbook records are keyed by
+	// the home call, so portable and reciprocal forms are reduced to it before querying.
+	cs, err := calls.Validate(callsign)
 	if err != nil {
-		return emptyRetVal, errors.New(op).Err(err).Msg("Failed to create HTTP GET request")
+		return emptyRetVal, errors.New(op).Err(err).Msg("invalid callsign")
 	}
+	callsign = cs.HomeCall
 
-	req.Header.Set("User-Agent", s.Config.UserAgent)
-	req.Header.Set("Accept", "application/xml")
-
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return emptyRetVal, errors.New(op).Err(err).Msg("Failed to perform HTTP GET request")
+	if err = s.limiter.Admit(ctx); err != nil {
+		return emptyRetVal, errors.New(op).Err(err).Msg("QRZ.com is held back by the rate limiter")
 	}
-	defer func(Body io.ReadCloser) {
-		_ = Body.Close()
-	}(resp.Body)
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		b, _ := io.ReadAll(resp.Body)
-		return emptyRetVal, errors.New(op).Errorf("Service returned unexpected status %d: %s", resp.StatusCode, string(b))
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return emptyRetVal, errors.New(op).Errorf("Failed to read response body: %w", err)
-	}
-
-	station, err := s.unmarshalResponse(body)
-	//TODO: handle 'Not found'
+	// Concurrent lookups of the same call share one request (and one quota hit); each
+	// caller still returns as soon as its own context is done.
+	result, _, err := s.inflight.Do(ctx, callsign, func(ctx context.Context) (Result, error) {
+		var result Result
+		err := s.withSession(ctx, func(key string) error {
+			body, err := s.fetch(ctx, key, url.Values{"callsign": {callsign}})
+			if err != nil {
+				return err
+			}
+			result.Station, result.Session, err = s.unmarshalResponse(body)
+			return err
+		})
+		result.Limited = result.Session.NonSubscriber
+		return result, err
+	})
 	if err != nil {
 		if stderr.Is(err, errors.ErrNotFound) {
 			s.LoggerService.InfoWith().Str("callsign", callsign).Msg("Callsign not found in QRZ.com database")
-			return types.ContactedStation{Call: callsign}, nil
+			return Result{Station: types.ContactedStation{Call: callsign}, Session: result.Session}, errors.New(op).Err(err).Msgf("%s not found in QRZ.com database", callsign)
 		}
-		return emptyRetVal, errors.New(op).Err(err).Msg("Failed to unmarshal response body")
+		return emptyRetVal, errors.New(op).Errorf("QRZ.com callsign lookup failed: %w", err)
 	}
 
-	return station, nil
+	return result, nil
+}
+
+// SessionInfo returns the subscription and quota details reported by the most recent
+// QRZ.com response. It is the zero value until the first response has been received.
+func (s *Service) SessionInfo() SessionInfo {
+	s.sessionMu.Lock()
+	defer s.sessionMu.Unlock()
+	return s.sessionInfo
 }
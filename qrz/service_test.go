@@ -0,0 +1,424 @@
+package qrz
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Station-Manager/logging"
+	"github.com/Station-Manager/lookup/internal/calls"
+	"github.com/Station-Manager/lookup/internal/upstream"
+	"github.com/Station-Manager/lookup/observer"
+	"github.com/Station-Manager/lookup/retry"
+	"github.com/Station-Manager/lookup/transport"
+	"github.com/Station-Manager/types"
+)
+
+const sessionXML = `<?xml version="1.0"?>
+<QRZDatabase version="1.34">
+  <Session><Key>abc123</Key><Count>1</Count></Session>
+</QRZDatabase>`
+
+func TestService_Initialize_MissingLogger(t *testing.T) {
+	s := NewService(nil, nil, &types.LookupConfig{}, nil)
+
+	if err := s.Initialize(); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+}
+
+func TestService_Initialize_Disabled(t *testing.T) {
+	s := NewService(&logging.Service{}, nil, &types.LookupConfig{Enabled: false}, nil)
+
+	if err := s.Initialize(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if key := s.session.Token(); key != "" {
+		t.Fatalf("expected no session key for a disabled service, got %q", key)
+	}
+}
+
+func TestService_Lookup_DisabledMatchesLookupDetailed(t *testing.T) {
+	s := NewService(&logging.Service{}, nil, &types.LookupConfig{Enabled: false}, nil)
+	if err := s.Initialize(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	station, err := s.Lookup("K1ABC")
+	if !stderrors.Is(err, upstream.ErrDisabled) {
+		t.Fatalf("expected ErrDisabled from Lookup, got %v", err)
+	}
+	result, err := s.LookupDetailed(context.Background(), "K1ABC")
+	if !stderrors.Is(err, upstream.ErrDisabled) {
+		t.Fatalf("expected ErrDisabled from LookupDetailed, got %v", err)
+	}
+	if station != result.Station {
+		t.Fatalf("expected the same station from both, got %+v and %+v", station, result.Station)
+	}
+}
+
+func TestService_Initialize_InjectedClientFetchesSessionKey(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("username") != "tester" {
+			t.Errorf("expected username query parameter, got %q", r.URL.RawQuery)
+		}
+		_, _ = w.Write([]byte(sessionXML))
+	})
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	cfg := &types.LookupConfig{
+		Enabled:        true,
+		URL:            ts.URL,
+		UserAgent:      "test",
+		HttpTimeoutSec: 5,
+		Username:       "tester",
+		Password:       "secret",
+	}
+	s := NewService(&logging.Service{}, nil, cfg, ts.Client())
+
+	if err := s.Initialize(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if key := s.session.Token(); key != "abc123" {
+		t.Fatalf("expected session key to be set, got %q", key)
+	}
+}
+
+func TestService_Initialize_ObserverNeverSeesCredentials(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(sessionXML))
+	}))
+	defer ts.Close()
+
+	var seen []string
+	cfg := &types.LookupConfig{
+		Enabled:        true,
+		URL:            ts.URL,
+		UserAgent:      "test",
+		HttpTimeoutSec: 5,
+		Username:       "tester",
+		Password:       "secret",
+	}
+	s := NewService(&logging.Service{}, nil, cfg, ts.Client())
+	s.Observer = observer.Funcs{
+		HTTPRequest: func(_ context.Context, _ observer.Event, req *http.Request) {
+			seen = append(seen, req.URL.String())
+		},
+		HTTPResponse: func(_ context.Context, _ observer.Event, resp *http.Response, _ error) {
+			seen = append(seen, resp.Request.URL.String())
+		},
+	}
+
+	if err := s.Initialize(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(seen) != 2 {
+		t.Fatalf("expected the login to be observed, got %v", seen)
+	}
+	for _, u := range seen {
+		if strings.Contains(u, "secret") || strings.Contains(u, "tester") {
+			t.Fatalf("observer saw credentials: %s", u)
+		}
+	}
+}
+
+func TestService_Initialize_UsesConfiguredTransport(t *testing.T) {
+	var logins atomic.Int32
+	ts := newSessionServer(t, &logins, 0)
+	defer ts.Close()
+
+	var requests atomic.Int32
+	cfg := &types.LookupConfig{
+		Enabled:        true,
+		URL:            ts.URL,
+		UserAgent:      "test",
+		HttpTimeoutSec: 5,
+		Username:       "tester",
+		Password:       "secret",
+	}
+	s := NewService(&logging.Service{}, nil, cfg, nil)
+	s.Transport = transport.Config{RoundTripper: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		requests.Add(1)
+		return ts.Client().Transport.RoundTrip(r)
+	})}
+
+	if err := s.Initialize(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := s.LookupWithContext(context.Background(), "AA7BQ"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Fatalf("expected login and lookup to use the configured transport, got %d requests", got)
+	}
+
+	badCfg := *cfg
+	bad := NewService(&logging.Service{}, nil, &badCfg, nil)
+	bad.Transport = transport.Config{ProxyURL: "ftp://proxy.example"}
+	if err := bad.Initialize(); err == nil || !strings.Contains(err.Error(), "transport") {
+		t.Fatalf("expected a transport error, got %v", err)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// newSessionServer returns a QRZ.com stand-in that hands out numbered session keys on
+// login and rejects callsign queries made with any key other than the latest one.
+func newSessionServer(t *testing.T, logins *atomic.Int32, loginDelay time.Duration) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("username") != "" {
+			time.Sleep(loginDelay)
+			n := logins.Add(1)
+			_, _ = fmt.Fprintf(w, `<QRZDatabase version="1.34"><Session><Key>key%d</Key></Session></QRZDatabase>`, n)
+			return
+		}
+		if q.Get("s") != fmt.Sprintf("key%d", logins.Load()) {
+			_, _ = w.Write([]byte(`<QRZDatabase version="1.34"><Session><Error>Session Timeout</Error></Session></QRZDatabase>`))
+			return
+		}
+		_, _ = fmt.Fprintf(w, `<QRZDatabase version="1.34"><Callsign><call>%s</call></Callsign><Session><Key>%s</Key></Session></QRZDatabase>`, q.Get("callsign"), q.Get("s"))
+	}))
+}
+
+func newTestService(ts *httptest.Server) *Service {
+	cfg := &types.LookupConfig{
+		Enabled:        true,
+		URL:            ts.URL,
+		UserAgent:      "test",
+		HttpTimeoutSec: 5,
+		Username:       "tester",
+		Password:       "secret",
+	}
+	s := NewService(&logging.Service{}, nil, cfg, ts.Client())
+	s.isInitialized.Store(true)
+	return s
+}
+
+func TestService_LookupWithContext_RenewsExpiredSession(t *testing.T) {
+	var logins atomic.Int32
+	logins.Store(1)
+	ts := newSessionServer(t, &logins, 0)
+	defer ts.Close()
+
+	s := newTestService(ts)
+	s.session.Set("stale")
+
+	station, err := s.Lookup("AA7BQ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if station.Call != "AA7BQ" {
+		t.Fatalf("unexpected station: %#v", station)
+	}
+	if got := logins.Load(); got != 2 {
+		t.Fatalf("expected exactly one re-login, got %d logins", got-1)
+	}
+	if s.session.Token() != "key2" {
+		t.Fatalf("expected renewed session key, got %q", s.session.Token())
+	}
+}
+
+func TestService_LookupWithContext_ConcurrentRenewalLogsInOnce(t *testing.T) {
+	var logins atomic.Int32
+	logins.Store(1)
+	ts := newSessionServer(t, &logins, 50*time.Millisecond)
+	defer ts.Close()
+
+	s := newTestService(ts)
+	s.session.Set("key1")
+	logins.Store(2) // invalidate key1 on the server side
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.Lookup("AA7BQ"); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := logins.Load(); got != 3 {
+		t.Fatalf("expected a single shared re-login, got %d", got-2)
+	}
+}
+
+func TestService_LookupWithContext_RenewalFailure(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("username") != "" {
+			_, _ = w.Write([]byte(`<QRZDatabase version="1.34"><Session><Error>Username/password incorrect</Error></Session></QRZDatabase>`))
+			return
+		}
+		_, _ = w.Write([]byte(`<QRZDatabase version="1.34"><Session><Error>Invalid session key</Error></Session></QRZDatabase>`))
+	}))
+	defer ts.Close()
+
+	s := newTestService(ts)
+	s.session.Set("stale")
+
+	if _, err := s.Lookup("AA7BQ"); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+}
+
+func TestService_LookupWithContext_RetriesTransientLoginFailure(t *testing.T) {
+	var logins atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("username") != "" {
+			if logins.Add(1) == 1 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			_, _ = w.Write([]byte(`<QRZDatabase version="1.34"><Session><Key>fresh</Key></Session></QRZDatabase>`))
+			return
+		}
+		if q.Get("s") != "fresh" {
+			_, _ = w.Write([]byte(`<QRZDatabase version="1.34"><Session><Error>Session Timeout</Error></Session></QRZDatabase>`))
+			return
+		}
+		_, _ = fmt.Fprintf(w, `<QRZDatabase version="1.34"><Callsign><call>%s</call></Callsign><Session><Key>fresh</Key></Session></QRZDatabase>`, q.Get("callsign"))
+	}))
+	defer ts.Close()
+
+	s := newTestService(ts)
+	s.Retry = retry.Policy{MaxAttempts: 3, BaseDelay: time.Millisecond, RetryableStatus: []int{http.StatusBadGateway}}
+	s.session.Set("stale")
+
+	station, err := s.Lookup("AA7BQ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if station.Call != "AA7BQ" {
+		t.Fatalf("unexpected station: %#v", station)
+	}
+	if got := logins.Load(); got != 2 {
+		t.Fatalf("expected the failed login to be retried once, got %d logins", got)
+	}
+}
+
+func TestService_LookupDetailed_NonSubscriberIsLimited(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<QRZDatabase version="1.34"><Callsign><call>AA7BQ</call><fname>FRED</fname></Callsign><Session><Key>k</Key><Count>7</Count><SubExp>non-subscriber</SubExp></Session></QRZDatabase>`))
+	}))
+	defer ts.Close()
+
+	s := newTestService(ts)
+	s.session.Set("k")
+
+	result, err := s.LookupDetailed(context.Background(), "AA7BQ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Limited {
+		t.Fatalf("expected result to be flagged as limited")
+	}
+	if result.Station.Call != "AA7BQ" {
+		t.Fatalf("unexpected station: %#v", result.Station)
+	}
+	if info := s.SessionInfo(); info.LookupsToday != 7 || !info.NonSubscriber {
+		t.Fatalf("unexpected session info: %#v", info)
+	}
+}
+
+func TestService_LookupDetailed_ReportsOwnSession(t *testing.T) {
+	counts := map[string]int{"AA7BQ": 7, "K1ABC": 11}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := r.URL.Query().Get("callsign")
+		if call == "AA7BQ" {
+			// Stagger the answers so each lookup sees a different latest session.
+			time.Sleep(20 * time.Millisecond)
+		}
+		_, _ = fmt.Fprintf(w, `<QRZDatabase version="1.34"><Callsign><call>%s</call></Callsign><Session><Key>k</Key><Count>%d</Count></Session></QRZDatabase>`, call, counts[call])
+	}))
+	defer ts.Close()
+
+	s := newTestService(ts)
+	s.session.Set("k")
+
+	var wg sync.WaitGroup
+	results := make(map[string]Result)
+	var mu sync.Mutex
+	for call := range counts {
+		wg.Add(1)
+		go func(call string) {
+			defer wg.Done()
+			result, err := s.LookupDetailed(context.Background(), call)
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", call, err)
+				return
+			}
+			mu.Lock()
+			results[call] = result
+			mu.Unlock()
+		}(call)
+	}
+	wg.Wait()
+
+	for call, want := range counts {
+		if got := results[call].Session.LookupsToday; got != want {
+			t.Fatalf("%s: expected LookupsToday %d from its own response, got %d", call, want, got)
+		}
+	}
+}
+
+func TestService_LookupWithContext_QueriesHomeCall(t *testing.T) {
+	var logins atomic.Int32
+	logins.Store(1)
+	ts := newSessionServer(t, &logins, 0)
+	defer ts.Close()
+
+	s := newTestService(ts)
+	s.session.Set("key1")
+
+	station, err := s.Lookup("VP2E/AA7BQ/P")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if station.Call != "AA7BQ" {
+		t.Fatalf("expected home call to be queried, got %#v", station)
+	}
+}
+
+func TestService_LookupWithContext_ValidatesCallsign(t *testing.T) {
+	var logins atomic.Int32
+	logins.Store(1)
+	ts := newSessionServer(t, &logins, 0)
+	defer ts.Close()
+
+	s := newTestService(ts)
+	s.session.Set("key1")
+
+	_, err := s.Lookup("TEST")
+	var cerr *calls.CallsignError
+	if !stderrors.As(err, &cerr) || !stderrors.Is(err, upstream.ErrInvalidCallsign) {
+		t.Fatalf("expected a CallsignError, got %v", err)
+	}
+
+	station, err := s.Lookup("ａａ７ｂｑ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if station.Call != "AA7BQ" {
+		t.Fatalf("expected the normalised callsign to be queried, got %#v", station)
+	}
+}
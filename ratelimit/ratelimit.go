@@ -0,0 +1,193 @@
+// Package ratelimit provides the client-side token-bucket limiter used by the HTTP
+// lookup providers. A limiter can additionally be paused, which the providers do when an
+// upstream answers 429/503 with a Retry-After header.
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Station-Manager/lookup/internal/upstream"
+)
+
+// Config describes a token bucket. The zero value disables throttling.
+type Config struct {
+	// Rate is the sustained number of requests per second.
+	Rate float64 `json:"rate"`
+	// Burst is the number of requests that may be made back to back. Values below one
+	// are treated as one.
+	Burst int `json:"burst"`
+}
+
+// Limiter is a token-bucket rate limiter. A nil *Limiter never throttles.
+type Limiter struct {
+	cfg Config
+	now func() time.Time
+
+	mu          sync.Mutex
+	tokens      float64
+	last        time.Time // time tokens was last brought up to date
+	pausedUntil time.Time
+}
+
+// New returns a limiter for cfg, starting with a full bucket.
+func New(cfg Config) *Limiter {
+	if cfg.Burst < 1 {
+		cfg.Burst = 1
+	}
+	return &Limiter{cfg: cfg, now: time.Now, tokens: float64(cfg.Burst)}
+}
+
+// Config returns the limiter's configuration.
+func (l *Limiter) Config() Config {
+	if l == nil {
+		return Config{}
+	}
+	return l.cfg
+}
+
+// Wait blocks until a request may be made. If the wait would outlast ctx's deadline it
+// returns a *upstream.RateLimitError (matched by lookup.ErrRateLimited) immediately
+// rather than waiting in vain; if ctx is cancelled while waiting it returns ctx.Err().
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	delay := l.reserve()
+	if delay <= 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		l.cancel()
+		return &upstream.RateLimitError{RetryAfter: delay}
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.cancel()
+		return ctx.Err()
+	}
+}
+
+// Admit returns a *upstream.RateLimitError if a request made now would have to wait,
+// for a pause or for a token, longer than ctx's deadline allows. It takes no token.
+// Providers call it on the caller's own context before joining a shared (coalesced)
+// request, whose context has no deadline.
+func (l *Limiter) Admit(ctx context.Context) error {
+	if l == nil || ctx == nil {
+		return nil
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil
+	}
+	if delay := l.delay(); delay > 0 && time.Until(deadline) < delay {
+		return &upstream.RateLimitError{RetryAfter: delay}
+	}
+	return nil
+}
+
+// Pause holds back all requests for d, e.g. as instructed by a Retry-After header.
+// Pauses never shorten one already in effect.
+func (l *Limiter) Pause(d time.Duration) {
+	if l == nil || d <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	until := l.now().Add(d)
+	if until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+	// The bucket refills only once the pause is over.
+	if l.tokens > 0 {
+		l.tokens = 0
+	}
+	if l.pausedUntil.After(l.last) {
+		l.last = l.pausedUntil
+	}
+}
+
+// PausedFor returns how much of the current pause remains.
+func (l *Limiter) PausedFor() time.Duration {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if d := l.pausedUntil.Sub(l.now()); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// reserve takes a token, going into debt if none is available, and returns how long
+// the caller must wait before using it.
+func (l *Limiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if l.cfg.Rate <= 0 {
+		// Unthrottled, but pauses still apply.
+		return l.pausedUntil.Sub(now)
+	}
+
+	l.refill(now)
+	l.tokens--
+	readyAt := l.last
+	if l.tokens < 0 {
+		readyAt = readyAt.Add(time.Duration(-l.tokens / l.cfg.Rate * float64(time.Second)))
+	}
+	return readyAt.Sub(now)
+}
+
+// delay returns how long a request made now would wait, like reserve but without
+// taking a token.
+func (l *Limiter) delay() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if l.cfg.Rate <= 0 {
+		return l.pausedUntil.Sub(now)
+	}
+
+	l.refill(now)
+	readyAt := l.last
+	if l.tokens < 1 {
+		readyAt = readyAt.Add(time.Duration((1 - l.tokens) / l.cfg.Rate * float64(time.Second)))
+	}
+	return readyAt.Sub(now)
+}
+
+// refill adds the tokens earned since last, up to the burst. l.mu must be held.
+func (l *Limiter) refill(now time.Time) {
+	if now.After(l.last) {
+		l.tokens += now.Sub(l.last).Seconds() * l.cfg.Rate
+		if burst := float64(l.cfg.Burst); l.tokens > burst {
+			l.tokens = burst
+		}
+		l.last = now
+	}
+}
+
+// cancel returns a token taken by reserve that will not be used.
+func (l *Limiter) cancel() {
+	if l.cfg.Rate <= 0 {
+		return
+	}
+	l.mu.Lock()
+	l.tokens++
+	l.mu.Unlock()
+}
@@ -0,0 +1,97 @@
+package ratelimit
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+	"time"
+
+	"github.com/Station-Manager/lookup/internal/upstream"
+)
+
+func TestLimiter_AllowsBurstThenThrottles(t *testing.T) {
+	l := New(Config{Rate: 20, Burst: 2})
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// Two requests use the burst; the third waits ~50ms for a token.
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Fatalf("expected the third request to be throttled, took %v", elapsed)
+	}
+}
+
+func TestLimiter_ReturnsRateLimitedWhenDeadlineTooShort(t *testing.T) {
+	l := New(Config{Rate: 1, Burst: 1})
+	_ = l.Wait(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := l.Wait(ctx)
+	var rle *upstream.RateLimitError
+	if !stderrors.As(err, &rle) || !stderrors.Is(err, upstream.ErrRateLimited) {
+		t.Fatalf("expected RateLimitError, got %v", err)
+	}
+	if rle.RetryAfter <= 0 {
+		t.Fatalf("expected a positive RetryAfter, got %v", rle.RetryAfter)
+	}
+}
+
+func TestLimiter_AdmitChecksBucketAgainstDeadline(t *testing.T) {
+	l := New(Config{Rate: 1, Burst: 1})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Admit(ctx); err != nil {
+		t.Fatalf("expected a full bucket to admit, got %v", err)
+	}
+	// Admit takes no token, so the bucket is still full.
+	if err := l.Admit(ctx); err != nil {
+		t.Fatalf("expected Admit not to take a token, got %v", err)
+	}
+
+	_ = l.Wait(context.Background())
+	err := l.Admit(ctx)
+	var rle *upstream.RateLimitError
+	if !stderrors.As(err, &rle) || rle.RetryAfter <= 0 {
+		t.Fatalf("expected RateLimitError for an empty bucket, got %v", err)
+	}
+	if err = l.Admit(context.Background()); err != nil {
+		t.Fatalf("expected a caller without a deadline to be admitted, got %v", err)
+	}
+}
+
+func TestLimiter_PauseHoldsRequests(t *testing.T) {
+	l := New(Config{})
+	l.Pause(50 * time.Millisecond)
+
+	if d := l.PausedFor(); d <= 0 {
+		t.Fatalf("expected an active pause")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); !stderrors.Is(err, upstream.ErrRateLimited) {
+		t.Fatalf("expected rate-limited error during pause, got %v", err)
+	}
+
+	start := time.Now()
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if time.Since(start) < 20*time.Millisecond {
+		t.Fatalf("expected Wait to block until the pause ended")
+	}
+}
+
+func TestLimiter_NilNeverThrottles(t *testing.T) {
+	var l *Limiter
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	l.Pause(time.Second)
+}
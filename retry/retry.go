@@ -0,0 +1,133 @@
+// Package retry implements the exponential-backoff retry policy the HTTP lookup
+// providers apply to their upstream requests.
+package retry
+
+import (
+	"context"
+	stderr "errors"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/Station-Manager/errors"
+	"github.com/Station-Manager/lookup/internal/upstream"
+)
+
+// Policy describes when and how often a failed request is retried. A Policy with a
+// zero MaxAttempts is treated by the providers as DefaultPolicy.
+type Policy struct {
+	// MaxAttempts is the total number of attempts, including the first. One disables
+	// retrying.
+	MaxAttempts int `json:"max_attempts"`
+	// BaseDelay is the wait before the first retry; it doubles for every further retry.
+	BaseDelay time.Duration `json:"base_delay"`
+	// MaxDelay caps the wait between attempts.
+	MaxDelay time.Duration `json:"max_delay"`
+	// Jitter randomises each wait by up to this fraction (0-1) in either direction, so
+	// that clients do not retry in lockstep.
+	Jitter float64 `json:"jitter"`
+	// RetryableStatus lists the HTTP statuses worth retrying. Network errors and
+	// timeouts are always retryable.
+	RetryableStatus []int `json:"retryable_status"`
+}
+
+// DefaultPolicy retries twice on network errors and gateway-type 5xx responses.
+var DefaultPolicy = Policy{
+	MaxAttempts: 3,
+	BaseDelay:   200 * time.Millisecond,
+	MaxDelay:    2 * time.Second,
+	Jitter:      0.2,
+	RetryableStatus: []int{
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	},
+}
+
+// Attempt describes a failed attempt.
+type Attempt struct {
+	// Number is the 1-based number of the attempt that failed.
+	Number int
+	Err    error
+	// Retrying reports whether another attempt follows, after Delay.
+	Retrying bool
+	Delay    time.Duration
+}
+
+// Do calls fn until it succeeds, fails with an error that is not retryable, runs out of
+// attempts, or would have to wait past ctx's deadline. onFailure, if not nil, is called
+// after every failed attempt. The error of the last attempt is returned.
+func (p Policy) Do(ctx context.Context, fn func(ctx context.Context) error, onFailure func(Attempt)) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	attempts := p.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	for n := 1; ; n++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+
+		a := Attempt{Number: n, Err: err}
+		if n < attempts && ctx.Err() == nil && p.Retryable(err) {
+			a.Delay = p.backoff(n)
+			deadline, ok := ctx.Deadline()
+			a.Retrying = !ok || time.Until(deadline) > a.Delay
+		}
+		if onFailure != nil {
+			onFailure(a)
+		}
+		if !a.Retrying {
+			return err
+		}
+
+		timer := time.NewTimer(a.Delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// Retryable reports whether err is worth another attempt. Not-found results, rate
+// limits (which pause the provider instead) and any status outside RetryableStatus,
+// including authentication failures, are not.
+func (p Policy) Retryable(err error) bool {
+	switch {
+	case err == nil,
+		stderr.Is(err, errors.ErrNotFound),
+		stderr.Is(err, upstream.ErrRateLimited),
+		stderr.Is(err, context.Canceled):
+		return false
+	}
+
+	var se *upstream.StatusError
+	if stderr.As(err, &se) {
+		return slices.Contains(p.RetryableStatus, se.StatusCode)
+	}
+	return upstream.IsConnectionFailure(err)
+}
+
+// backoff returns the wait after the n-th failed attempt.
+func (p Policy) backoff(n int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < n && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 && d > 0 {
+		j := min(p.Jitter, 1)
+		d = time.Duration(float64(d) * (1 + j*(2*rand.Float64()-1)))
+	}
+	return d
+}
@@ -0,0 +1,80 @@
+package retry
+
+import (
+	"context"
+	stderrors "errors"
+	"net"
+	"testing"
+	"time"
+
+	smerrors "github.com/Station-Manager/errors"
+	"github.com/Station-Manager/lookup/internal/upstream"
+)
+
+var fastPolicy = Policy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond, RetryableStatus: []int{502}}
+
+func TestPolicy_RetriesTransientFailures(t *testing.T) {
+	var attempts []Attempt
+	n := 0
+	err := fastPolicy.Do(context.Background(), func(context.Context) error {
+		n++
+		if n < 3 {
+			return smerrors.New("test").Err(&upstream.StatusError{StatusCode: 502}).Msg("bad gateway")
+		}
+		return nil
+	}, func(a Attempt) { attempts = append(attempts, a) })
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 3 || len(attempts) != 2 || !attempts[0].Retrying || attempts[1].Number != 2 {
+		t.Fatalf("unexpected attempts: n=%d %#v", n, attempts)
+	}
+}
+
+func TestPolicy_DoesNotRetryPermanentFailures(t *testing.T) {
+	for name, failure := range map[string]error{
+		"not found":    smerrors.New("test").Err(smerrors.ErrNotFound).Msg("not found"),
+		"unauthorized": &upstream.StatusError{StatusCode: 401},
+		"rate limited": &upstream.RateLimitError{StatusCode: 429},
+	} {
+		n := 0
+		_ = fastPolicy.Do(context.Background(), func(context.Context) error {
+			n++
+			return failure
+		}, nil)
+		if n != 1 {
+			t.Fatalf("%s: expected a single attempt, got %d", name, n)
+		}
+	}
+}
+
+func TestPolicy_GivesUpAfterMaxAttempts(t *testing.T) {
+	netErr := &net.OpError{Op: "read", Net: "tcp", Err: stderrors.New("connection reset by peer")}
+	var last Attempt
+	n := 0
+	err := fastPolicy.Do(context.Background(), func(context.Context) error {
+		n++
+		return netErr
+	}, func(a Attempt) { last = a })
+
+	if !stderrors.Is(err, netErr) || n != 3 || last.Retrying {
+		t.Fatalf("expected 3 attempts ending in the network error, got n=%d err=%v last=%#v", n, err, last)
+	}
+}
+
+func TestPolicy_RespectsDeadline(t *testing.T) {
+	slow := Policy{MaxAttempts: 5, BaseDelay: time.Second, RetryableStatus: []int{502}}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	n := 0
+	_ = slow.Do(ctx, func(context.Context) error {
+		n++
+		return &upstream.StatusError{StatusCode: 502}
+	}, nil)
+	if n != 1 || time.Since(start) > 40*time.Millisecond {
+		t.Fatalf("expected no retry that would outlast the deadline, got %d attempts in %v", n, time.Since(start))
+	}
+}
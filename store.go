@@ -0,0 +1,349 @@
+package lookup
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Station-Manager/errors"
+	"github.com/Station-Manager/lookup/internal/filelock"
+	"github.com/goccy/go-json"
+)
+
+const (
+	// DefaultFileStoreMaxEntries is the entry cap used when FileStoreOptions.MaxEntries is unset.
+	DefaultFileStoreMaxEntries = 50000
+	// DefaultFileStoreMaxBytes is the size cap used when FileStoreOptions.MaxBytes is unset.
+	DefaultFileStoreMaxBytes = 32 << 20
+)
+
+// CacheStore is a persistent second-level store for the caching wrappers (see
+// CacheOptions.Store). Implementations must be safe for concurrent use.
+type CacheStore interface {
+	// Get returns the entry stored under key. Expired entries may be returned; the
+	// caller checks StoredEntry.Expires.
+	Get(key string) (StoredEntry, bool, error)
+	Put(key string, entry StoredEntry) error
+	Delete(key string) error
+}
+
+// StoredEntry is a cached lookup result as held by a CacheStore.
+type StoredEntry struct {
+	// Value is the JSON-encoded result. It is empty for not-found entries.
+	Value    []byte
+	NotFound bool
+	Expires  time.Time
+}
+
+// FileStoreOptions configures a FileStore.
+type FileStoreOptions struct {
+	// MaxEntries caps the number of live entries; the oldest written are dropped first
+	// when the store is compacted.
+	MaxEntries int
+	// MaxBytes caps the size of the store file.
+	MaxBytes int64
+}
+
+// FileStore is the default CacheStore. It keeps entries in an append-only JSON-lines
+// file that is compacted (expired, overwritten and deleted entries dropped, size caps
+// applied) once enough garbage accumulates. Every operation takes an advisory lock on a
+// sibling ".lock" file, so several Station Manager instances on the same machine can
+// share one store.
+type FileStore struct {
+	path     string
+	lockPath string
+	opts     FileStoreOptions
+
+	mu      sync.Mutex
+	index   map[string]fileRecord
+	info    os.FileInfo // identity of the file the index was built from
+	offset  int64       // bytes of that file consumed so far
+	records int         // lines in that file, including garbage
+}
+
+// fileRecord is one line of the store file.
+type fileRecord struct {
+	Key      string    `json:"k"`
+	Value    []byte    `json:"v,omitempty"`
+	NotFound bool      `json:"nf,omitempty"`
+	Deleted  bool      `json:"d,omitempty"`
+	Expires  time.Time `json:"e"`
+	Written  time.Time `json:"w"`
+}
+
+// NewFileStore opens (creating if necessary) the store at path.
+func NewFileStore(path string, opts FileStoreOptions) (*FileStore, error) {
+	const op errors.Op = "lookup.NewFileStore"
+	if path == "" {
+		return nil, errors.New(op).Msg("store path cannot be empty")
+	}
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = DefaultFileStoreMaxEntries
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultFileStoreMaxBytes
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, errors.New(op).Err(err).Msg("failed to create store directory")
+	}
+
+	s := &FileStore{path: path, lockPath: path + ".lock", opts: opts, index: make(map[string]fileRecord)}
+	if err := s.withLock(true, s.compactLocked); err != nil {
+		return nil, errors.New(op).Err(err).Msg("failed to open store")
+	}
+	return s, nil
+}
+
+// Get returns the entry stored under key, including entries written by other processes.
+func (s *FileStore) Get(key string) (StoredEntry, bool, error) {
+	const op errors.Op = "lookup.FileStore.Get"
+	var entry StoredEntry
+	var found bool
+	err := s.withLock(false, func() error {
+		if err := s.refreshLocked(); err != nil {
+			return err
+		}
+		rec, ok := s.index[key]
+		if ok {
+			entry = StoredEntry{Value: rec.Value, NotFound: rec.NotFound, Expires: rec.Expires}
+			found = true
+		}
+		return nil
+	})
+	if err != nil {
+		return StoredEntry{}, false, errors.New(op).Err(err).Msg("failed to read store")
+	}
+	return entry, found, nil
+}
+
+// Put stores entry under key, compacting the file when it has grown past its caps or
+// holds mostly garbage.
+func (s *FileStore) Put(key string, entry StoredEntry) error {
+	const op errors.Op = "lookup.FileStore.Put"
+	rec := fileRecord{Key: key, Value: entry.Value, NotFound: entry.NotFound, Expires: entry.Expires, Written: time.Now().UTC()}
+	if err := s.withLock(true, func() error { return s.appendLocked(rec) }); err != nil {
+		return errors.New(op).Err(err).Msg("failed to write store")
+	}
+	return nil
+}
+
+// Delete removes the entry stored under key.
+func (s *FileStore) Delete(key string) error {
+	const op errors.Op = "lookup.FileStore.Delete"
+	rec := fileRecord{Key: key, Deleted: true, Written: time.Now().UTC()}
+	if err := s.withLock(true, func() error { return s.appendLocked(rec) }); err != nil {
+		return errors.New(op).Err(err).Msg("failed to write store")
+	}
+	return nil
+}
+
+// Compact rewrites the store file without expired, overwritten or deleted entries and
+// enforces the size caps.
+func (s *FileStore) Compact() error {
+	const op errors.Op = "lookup.FileStore.Compact"
+	if err := s.withLock(true, s.compactLocked); err != nil {
+		return errors.New(op).Err(err).Msg("failed to compact store")
+	}
+	return nil
+}
+
+// Len returns the number of live entries.
+func (s *FileStore) Len() (int, error) {
+	const op errors.Op = "lookup.FileStore.Len"
+	var n int
+	err := s.withLock(false, func() error {
+		if err := s.refreshLocked(); err != nil {
+			return err
+		}
+		n = len(s.index)
+		return nil
+	})
+	if err != nil {
+		return 0, errors.New(op).Err(err).Msg("failed to read store")
+	}
+	return n, nil
+}
+
+// withLock runs fn holding both the in-process mutex and the inter-process file lock.
+func (s *FileStore) withLock(exclusive bool, fn func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lf, err := os.OpenFile(s.lockPath, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = lf.Close() }()
+
+	if exclusive {
+		err = filelock.Lock(lf)
+	} else {
+		err = filelock.RLock(lf)
+	}
+	if err != nil {
+		return err
+	}
+	defer func() { _ = filelock.Unlock(lf) }()
+
+	return fn()
+}
+
+// refreshLocked brings the index up to date with the file, reading only what was
+// appended since the last refresh unless the file was replaced by a compaction.
+func (s *FileStore) refreshLocked() error {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		s.resetLocked(nil)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if s.info == nil || !os.SameFile(s.info, info) || info.Size() < s.offset {
+		s.resetLocked(info)
+	}
+	s.info = info
+	if info.Size() == s.offset {
+		return nil
+	}
+
+	if _, err = f.Seek(s.offset, io.SeekStart); err != nil {
+		return err
+	}
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// A trailing partial line is left for the next refresh.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		s.offset += int64(len(line))
+		s.records++
+
+		var rec fileRecord
+		if json.Unmarshal(bytes.TrimSpace(line), &rec) != nil || rec.Key == "" {
+			// Skip corrupt lines rather than losing the whole store.
+			continue
+		}
+		if rec.Deleted {
+			delete(s.index, rec.Key)
+		} else {
+			s.index[rec.Key] = rec
+		}
+	}
+}
+
+func (s *FileStore) resetLocked(info os.FileInfo) {
+	s.index = make(map[string]fileRecord)
+	s.info = info
+	s.offset = 0
+	s.records = 0
+}
+
+// appendLocked appends rec to the file and compacts if required.
+func (s *FileStore) appendLocked(rec fileRecord) error {
+	if err := s.refreshLocked(); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(line); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	// Pick our own line up through the normal path so offset and identity stay in step.
+	if err = s.refreshLocked(); err != nil {
+		return err
+	}
+
+	garbage := s.records - len(s.index)
+	if s.offset > s.opts.MaxBytes || len(s.index) > s.opts.MaxEntries || garbage > len(s.index)+100 {
+		return s.compactLocked()
+	}
+	return nil
+}
+
+// compactLocked rewrites the file with live, unexpired entries only, dropping the
+// oldest written entries until both caps are met. The new file replaces the old one
+// atomically.
+func (s *FileStore) compactLocked() error {
+	if err := s.refreshLocked(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	live := make([]fileRecord, 0, len(s.index))
+	for _, rec := range s.index {
+		if now.Before(rec.Expires) {
+			live = append(live, rec)
+		}
+	}
+	// Newest first, so truncating drops the oldest.
+	sort.Slice(live, func(i, j int) bool { return live[i].Written.After(live[j].Written) })
+	if len(live) > s.opts.MaxEntries {
+		live = live[:s.opts.MaxEntries]
+	}
+
+	var buf bytes.Buffer
+	kept := 0
+	for _, rec := range live {
+		line, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		if int64(buf.Len()+len(line)+1) > s.opts.MaxBytes {
+			break
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+		kept++
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	if _, err = tmp.Write(buf.Bytes()); err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmpName, s.path)
+	}
+	if err != nil {
+		_ = os.Remove(tmpName)
+		return err
+	}
+
+	s.resetLocked(nil)
+	return s.refreshLocked()
+}
@@ -0,0 +1,111 @@
+package lookup
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Station-Manager/types"
+)
+
+func TestFileStore_SharedBetweenInstances(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.jsonl")
+	a, err := NewFileStore(path, FileStoreOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := NewFileStore(path, FileStoreOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entry := StoredEntry{Value: []byte(`{"Name":"TestLand"}`), Expires: time.Now().Add(time.Hour)}
+	if err = a.Put("K1ABC", entry); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, ok, err := b.Get("K1ABC")
+	if err != nil || !ok || string(got.Value) != string(entry.Value) {
+		t.Fatalf("expected entry written by another instance, got %#v %v %v", got, ok, err)
+	}
+
+	if err = b.Delete("K1ABC"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok, _ = a.Get("K1ABC"); ok {
+		t.Fatalf("expected entry deleted by another instance to be gone")
+	}
+}
+
+func TestFileStore_CompactionDropsExpiredAndEnforcesCaps(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.jsonl")
+	s, err := NewFileStore(path, FileStoreOptions{MaxEntries: 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_ = s.Put("EXPIRED", StoredEntry{Value: []byte(`{}`), Expires: time.Now().Add(-time.Minute)})
+	for i := 0; i < 5; i++ {
+		_ = s.Put(fmt.Sprintf("K%dABC", i), StoredEntry{Value: []byte(`{}`), Expires: time.Now().Add(time.Hour)})
+		time.Sleep(time.Millisecond) // distinct write times
+	}
+	if err = s.Compact(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if n, _ := s.Len(); n != 3 {
+		t.Fatalf("expected 3 entries after compaction, got %d", n)
+	}
+	if _, ok, _ := s.Get("EXPIRED"); ok {
+		t.Fatalf("expected expired entry to be compacted away")
+	}
+	if _, ok, _ := s.Get("K0ABC"); ok {
+		t.Fatalf("expected oldest entry to be dropped by the entry cap")
+	}
+	if _, ok, _ := s.Get("K4ABC"); !ok {
+		t.Fatalf("expected newest entry to survive compaction")
+	}
+
+	reopened, err := NewFileStore(path, FileStoreOptions{MaxEntries: 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n, _ := reopened.Len(); n != 3 {
+		t.Fatalf("expected compacted entries to persist, got %d", n)
+	}
+}
+
+func TestCachedStationProvider_PersistsAcrossRestarts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.jsonl")
+	var calls atomic.Int32
+	upstream := funcStationProvider(func(_ context.Context, callsign string) (types.ContactedStation, error) {
+		calls.Add(1)
+		return types.ContactedStation{Call: callsign, Name: "Fred"}, nil
+	})
+
+	store, err := NewFileStore(path, FileStoreOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	first := NewCachedStationProvider(upstream, CacheOptions{Store: store, Namespace: "qrz"})
+	if _, err = first.Lookup("AA7BQ"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A fresh wrapper and store stand in for a restarted process.
+	store, err = NewFileStore(path, FileStoreOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second := NewCachedStationProvider(upstream, CacheOptions{Store: store, Namespace: "qrz"})
+	station, status, err := second.LookupCached(context.Background(), "AA7BQ")
+	if err != nil || status != CacheStoreHit || station.Name != "Fred" {
+		t.Fatalf("expected store hit, got %#v %v %v", station, status, err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("expected a single upstream call, got %d", got)
+	}
+}
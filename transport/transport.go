@@ -0,0 +1,138 @@
+// Package transport builds the HTTP clients the providers use to reach their upstreams,
+// so that stations behind filtering or TLS-intercepting proxies can supply a proxy, extra
+// certificate authorities, a client certificate or an http.RoundTripper of their own.
+package transport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Station-Manager/errors"
+	"github.com/Station-Manager/utils"
+)
+
+// Config describes how a provider reaches its upstream. The zero value gives the
+// default client: proxy taken from the environment (HTTPS_PROXY and friends) and the
+// system certificate authorities.
+type Config struct {
+	// ProxyURL sends requests through the given http, https or socks5 proxy instead of
+	// the one from the environment. Credentials may be included in the URL.
+	ProxyURL string `json:"proxy_url,omitempty"`
+	// CAFile names a PEM bundle of certificate authorities trusted in addition to the
+	// system ones, e.g. the root certificate of an intercepting proxy.
+	CAFile string `json:"ca_file,omitempty"`
+	// CAPEM is a PEM bundle given inline; it is trusted together with CAFile.
+	CAPEM []byte `json:"ca_pem,omitempty"`
+	// CertFile and KeyFile name the PEM client certificate and private key presented to
+	// servers that ask for one. Both must be set, or neither.
+	CertFile string `json:"cert_file,omitempty"`
+	KeyFile  string `json:"key_file,omitempty"`
+	// RoundTripper, if set, sends every request and the settings above are ignored.
+	RoundTripper http.RoundTripper `json:"-"`
+}
+
+// IsZero reports whether c selects the default client.
+func (c Config) IsZero() bool {
+	return c.ProxyURL == "" && c.CAFile == "" && len(c.CAPEM) == 0 && c.CertFile == "" && c.KeyFile == "" && c.RoundTripper == nil
+}
+
+// NewClient returns a client that gives up on a request after timeout (15s if timeout is
+// not positive) and otherwise behaves as c describes. Files are read now, so a missing or
+// malformed certificate is reported here rather than on the first lookup.
+func (c Config) NewClient(timeout time.Duration) (*http.Client, error) {
+	const op errors.Op = "transport.Config.NewClient"
+	client := utils.NewHTTPClient(timeout)
+	if c.RoundTripper != nil {
+		client.Transport = c.RoundTripper
+		return client, nil
+	}
+	if c.IsZero() {
+		return client, nil
+	}
+
+	base, ok := client.Transport.(*http.Transport)
+	if !ok {
+		return nil, errors.New(op).Msgf("unexpected default transport %T", client.Transport)
+	}
+	t := base.Clone()
+
+	if c.ProxyURL != "" {
+		proxy, err := parseProxyURL(c.ProxyURL)
+		if err != nil {
+			return nil, errors.New(op).Err(err).Msg("invalid proxy URL")
+		}
+		t.Proxy = http.ProxyURL(proxy)
+	}
+
+	if c.CAFile != "" || len(c.CAPEM) > 0 || c.CertFile != "" || c.KeyFile != "" {
+		tlsConfig, err := c.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		t.TLSClientConfig = tlsConfig
+	}
+
+	client.Transport = t
+	return client, nil
+}
+
+// tlsConfig builds the TLS settings for the certificate options.
+func (c Config) tlsConfig() (*tls.Config, error) {
+	const op errors.Op = "transport.Config.tlsConfig"
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if c.CAFile != "" || len(c.CAPEM) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if c.CAFile != "" {
+			pem, err := os.ReadFile(c.CAFile)
+			if err != nil {
+				return nil, errors.New(op).Err(err).Msg("failed to read CA bundle")
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, errors.New(op).Msgf("no certificates found in CA bundle %s", c.CAFile)
+			}
+		}
+		if len(c.CAPEM) > 0 && !pool.AppendCertsFromPEM(c.CAPEM) {
+			return nil, errors.New(op).Msg("no certificates found in CAPEM")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		if c.CertFile == "" || c.KeyFile == "" {
+			return nil, errors.New(op).Msg("client certificate and key must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, errors.New(op).Err(err).Msg("failed to load client certificate")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+func parseProxyURL(raw string) (*url.URL, error) {
+	const op errors.Op = "transport.parseProxyURL"
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, errors.New(op).Err(err).Msg("cannot parse proxy URL")
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, errors.New(op).Msgf("unsupported proxy scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, errors.New(op).Msg("proxy URL has no host")
+	}
+	return u, nil
+}
@@ -0,0 +1,152 @@
+package transport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestConfig_NewClient_UsesRoundTripper(t *testing.T) {
+	var calls atomic.Int32
+	c := Config{
+		ProxyURL: "ftp://ignored",
+		RoundTripper: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			calls.Add(1)
+			return &http.Response{StatusCode: http.StatusTeapot, Body: http.NoBody, Request: r}, nil
+		}),
+	}
+	client, err := c.NewClient(time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp, err := client.Get("http://example.invalid/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusTeapot || calls.Load() != 1 {
+		t.Fatalf("expected the round tripper to answer, got %d after %d calls", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestConfig_NewClient_Proxy(t *testing.T) {
+	var proxied atomic.Value
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied.Store(r.URL.String())
+		_, _ = io.WriteString(w, "via proxy")
+	}))
+	defer proxy.Close()
+
+	client, err := Config{ProxyURL: proxy.URL}.NewClient(time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp, err := client.Get("http://upstream.invalid/xml?callsign=K1ABC")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+	if got, _ := proxied.Load().(string); got != "http://upstream.invalid/xml?callsign=K1ABC" {
+		t.Fatalf("expected the request to go through the proxy, got %q", got)
+	}
+
+	for _, bad := range []string{"ftp://proxy.example", "http://", "::"} {
+		if _, err = (Config{ProxyURL: bad}).NewClient(time.Second); err == nil {
+			t.Fatalf("expected error for proxy URL %q, got nil", bad)
+		}
+	}
+}
+
+func TestConfig_NewClient_CustomCAAndClientCertificate(t *testing.T) {
+	var clientCerts atomic.Int32
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientCerts.Store(int32(len(r.TLS.PeerCertificates)))
+	}))
+	ts.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	ts.StartTLS()
+	defer ts.Close()
+
+	// The test server's certificate stands in for an intercepting proxy's root.
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+
+	// Without the extra CA the server is not trusted.
+	client, err := Config{}.NewClient(time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err = client.Get(ts.URL); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+
+	// Reuse the server's key pair as the client certificate.
+	dir := t.TempDir()
+	key, err := x509.MarshalPKCS8PrivateKey(ts.TLS.Certificates[0].PrivateKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	caFile := filepath.Join(dir, "ca.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	writeFile(t, caFile, caPEM)
+	writeFile(t, keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key}))
+
+	client, err = Config{CAFile: caFile, CertFile: caFile, KeyFile: keyFile}.NewClient(time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+	if clientCerts.Load() != 1 {
+		t.Fatalf("expected the client certificate to be presented, got %d", clientCerts.Load())
+	}
+
+	// Inline PEM works as well.
+	client, err = Config{CAPEM: caPEM}.NewClient(time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp, err = client.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = resp.Body.Close()
+}
+
+func TestConfig_NewClient_InvalidCertificates(t *testing.T) {
+	dir := t.TempDir()
+	junk := filepath.Join(dir, "junk.pem")
+	writeFile(t, junk, []byte("not a certificate"))
+
+	for name, c := range map[string]Config{
+		"missing CA file":  {CAFile: filepath.Join(dir, "missing.pem")},
+		"CA file not PEM":  {CAFile: junk},
+		"CAPEM not PEM":    {CAPEM: []byte("junk")},
+		"cert without key": {CertFile: junk},
+		"bad key pair":     {CertFile: junk, KeyFile: junk},
+	} {
+		if _, err := c.NewClient(time.Second); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}